@@ -1,27 +1,60 @@
 package malarkey
 
+import "fmt"
+
 // Value is a mal value with explicit type.
-// Types:
-// * "list"         - []Value
-// * "vector"       - []Value
-// * "hash-map"     - map[string]Value
-// * "symbol"       - string
-// * "string"       - string
-// * "integer"      - int64
-// * "float"        - float64
-// * "boolean"      - bool
-// * "nil"          - nil
-// * "atom"         - int. atom id (`atoms` idx) hack to dig myself out of non-pointer vals. i liked the bias to immutability
-// * "function"     - func(args ...Value) Value
-// * "function-tco" - {
-//   - AST:    Value
-//   - Params: Value
-//   - Env:    *Env
-//   - Fn:     func(args ...Value) Value
-//   - IsMacro bool                      }
+// Kinds:
+// * KindList          - []Value
+// * KindVector        - []Value
+// * KindHashMap       - map[string]MapEntry. entries keyed by the hashKey of their key
+// * KindSet           - map[string]Value. elements keyed by their hashKey
+// * KindSymbol        - string
+// * KindString        - string
+// * KindChar          - rune
+// * KindInteger       - int64
+// * KindFloat         - float64
+// * KindBigInt        - *big.Int
+// * KindRatio         - *big.Rat
+// * KindBoolean       - bool
+// * KindKeyword       - string. with its leading colon
+// * KindRegex         - *regexp.Regexp
+// * KindInst          - time.Time. in UTC
+// * KindUUID          - string. lowercase
+// * KindTaggedLiteral - TaggedLiteral. a `#tag form` literal with an unknown tag
+// * KindNil           - nil
+// * KindAtom          - *Atom. a mutable reference
+// * KindFunction      - func(args ...Value) Value
+// * KindFunctionTCO   - FunctionTCO
 type Value struct {
-	Type string
+	Type Kind
 	Val  interface{}
+	Span *Span  // source location of the form. nil for values not produced by the reader
+	Meta *Value // metadata hash-map of collections, symbols and functions. nil if none
+}
+
+// Pos is a 1-indexed line and column (in runes) in source text.
+type Pos struct {
+	Line   int
+	Col    int
+	Offset int // 0-indexed byte offset
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Span is the source range of a form read by the reader. End is exclusive.
+type Span struct {
+	File  string
+	Start Pos
+	End   Pos
+}
+
+func (s *Span) String() string {
+	if s.File == "" {
+		return s.Start.String()
+	}
+	return fmt.Sprintf("%s:%s", s.File, s.Start)
 }
 
 // FunctionTCO is a `fn*`-defined function that can be evaluated in a TCO style.
@@ -31,4 +64,6 @@ type FunctionTCO struct {
 	Env     *Env
 	Fn      func(args ...Value) Value
 	IsMacro bool
+	Name    string // name of the def! or defmacro! that first bound the function. empty if anonymous
+	Span    *Span  // source location of the fn* form. nil if it was not read from source
 }
@@ -1,10 +1,20 @@
 package malarkey
 
-// This is a gross hack to dig myself out of the fact that I'm not using pointers
-// and my variables are immutable. I don't have a mechanism to carry around the
-// atom values and make mutable.
+// Atom is a mutable reference to a value. Atoms are shared by pointer, so every copy of an atom Value refers to the
+// same Atom, and an atom is garbage collected with the last value that refers to it.
+type Atom struct {
+	Val Value
+	ID  int // number of the atom in the interpreter that created it, counting from 0. used to print cycles
+}
 
-// Global atom list from atom id (idx) to current atom value.
-// This is a internal implementation detail that works in service of Env.
-// No mutex needed because this is single threaded.
-var atoms []Value
+// atomCounter numbers the atoms of an interpreter. No mutex needed because an interpreter is single threaded.
+type atomCounter struct {
+	next int
+}
+
+// newAtom creates an atom value with the next ID of the counter.
+func (c *atomCounter) newAtom(val Value) Value {
+	a := &Atom{Val: val, ID: c.next}
+	c.next++
+	return Value{Type: KindAtom, Val: a}
+}
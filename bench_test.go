@@ -0,0 +1,113 @@
+package malarkey_test
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+// regexTokenRegex is the regex tokenizer that the hand-written lexer replaced. Kept as a baseline for comparison.
+var regexTokenRegex = regexp.MustCompile(`[\s,]*(~@|[\[\]{}()'` + "`" + `~^@]|"(?:\\.|[^\\"])*"?|;.*|[^\s\[\]{}('"` + "`" + `,;)]*)`)
+
+func regexTokenize(input string) []string {
+	var out []string
+	for _, match := range regexTokenRegex.FindAllStringSubmatch(input, -1) {
+		if match[1] == "" || strings.HasPrefix(match[1], ";") {
+			continue
+		}
+		out = append(out, match[1])
+	}
+	return out
+}
+
+// largeSource returns a mal source of roughly n forms exercising every token kind.
+func largeSource(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "; form %d\n(def! f%d (fn* [a b & more] (let* [s \"str \\\"%d\\\"\" k :key] `(~a ~@more {\"k\" %d.5}))))\n", i, i, i, i)
+	}
+	return b.String()
+}
+
+// largeList returns a list of n nested vectors.
+func largeList(n int) mal.Value {
+	elems := make([]mal.Value, n)
+	for i := range elems {
+		elems[i] = mal.Value{Type: mal.KindVector, Val: []mal.Value{{Type: mal.KindInteger, Val: int64(i)}, {Type: mal.KindKeyword, Val: ":k"}}}
+	}
+	return mal.Value{Type: mal.KindList, Val: elems}
+}
+
+// benchmarkEval evaluates expr in an env where setup has been evaluated.
+func benchmarkEval(b *testing.B, setup []string, expr string) {
+	env := mal.BuiltinEnv()
+	for _, form := range setup {
+		mal.Eval(mal.Read(form), env)
+	}
+	ast := mal.Read(expr)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mal.Eval(ast, env)
+	}
+}
+
+// Run with `make bench`.
+
+func BenchmarkTokenizeRegex(b *testing.B) {
+	src := largeSource(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		regexTokenize(src)
+	}
+}
+
+func BenchmarkTokenizeLexer(b *testing.B) {
+	src := largeSource(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mal.Tokenize(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkEval(b, []string{
+		`(def! fib (fn* [n] (if (< n 2) n (+ (fib (- n 1)) (fib (- n 2))))))`,
+	}, `(fib 20)`)
+}
+
+func BenchmarkAckermann(b *testing.B) {
+	benchmarkEval(b, []string{
+		`(def! ack (fn* [m n] (if (= m 0) (+ n 1) (if (= n 0) (ack (- m 1) 1) (ack (- m 1) (ack m (- n 1)))))))`,
+	}, `(ack 2 200)`)
+}
+
+func BenchmarkListProcessing(b *testing.B) {
+	benchmarkEval(b, []string{
+		`(def! range* (fn* [n acc] (if (= n 0) acc (range* (- n 1) (cons n acc)))))`,
+		`(def! sum (fn* [xs acc] (if (empty? xs) acc (sum (rest xs) (+ acc (first xs))))))`,
+	}, `(sum (map (fn* [x] (* x x)) (range* 1000 (list))) 0)`)
+}
+
+func BenchmarkPrintLargeList(b *testing.B) {
+	list := largeList(1000000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mal.Print(list, true)
+	}
+}
+
+func BenchmarkFprintLargeList(b *testing.B) {
+	list := largeList(1000000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := mal.Fprint(io.Discard, list, mal.PrintOptions{Readably: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
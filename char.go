@@ -0,0 +1,65 @@
+package malarkey
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// named character literals, e.g. `\newline`
+var charNames = map[string]rune{
+	"newline":   '\n',
+	"space":     ' ',
+	"tab":       '\t',
+	"return":    '\r',
+	"backspace": '\b',
+	"formfeed":  '\f',
+}
+
+// parseChar parses a character literal token such as `\a`, `\newline` or `é`.
+func parseChar(token string) (Value, error) {
+	name := token[1:]
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		return Value{Type: KindChar, Val: r}, nil
+	}
+	if r, ok := charNames[name]; ok {
+		return Value{Type: KindChar, Val: r}, nil
+	}
+	if len(name) == 5 && name[0] == 'u' {
+		if code, err := strconv.ParseUint(name[1:], 16, 16); err == nil {
+			return Value{Type: KindChar, Val: rune(code)}, nil
+		}
+	}
+	return Value{}, fmt.Errorf("invalid character literal %s", token)
+}
+
+// printChar returns the character literal of r.
+func printChar(r rune) string {
+	for name, named := range charNames {
+		if r == named {
+			return `\` + name
+		}
+	}
+	if !unicode.IsPrint(r) && r <= 0xffff {
+		return fmt.Sprintf(`\u%04x`, r)
+	}
+	return `\` + string(r)
+}
+
+// seqElems returns the elements of a list or vector or the characters of a string.
+func seqElems(v Value) []Value {
+	if v.Type == KindString {
+		return stringChars(v.Str())
+	}
+	return v.Elems()
+}
+
+// stringChars returns the characters of a string.
+func stringChars(s string) []Value {
+	var chars []Value
+	for _, r := range s {
+		chars = append(chars, Value{Type: KindChar, Val: r})
+	}
+	return chars
+}
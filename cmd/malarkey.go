@@ -2,22 +2,77 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	mal "github.com/elh/mal-arkey"
 )
 
+// Print a recovered panic as an error. deferred so that the REPL can continue accepting stdin
+func printPanic() {
+	if r := recover(); r != nil {
+		const colorRed, colorReset = "\033[31m", "\033[0m"
+		fmt.Printf("%sError: %s%s", colorRed, r, colorReset)
+	}
+}
+
 // Read–eval–print. recover panics here so that REPL can continue accepting stdin
 func rep(str string, env *mal.Env) (out string) {
-	defer func() {
-		if r := recover(); r != nil {
+	defer printPanic()
+	// read with the reader features and data readers of env. the result of the last form is printed
+	reader := mal.NewEnvReader(env, strings.NewReader(str), "")
+	for {
+		form, err := reader.ReadForm()
+		if err == io.EOF {
+			return out
+		} else if err != nil {
+			panic(err)
+		}
+		out = env.Print(mal.Eval(form, env), true)
+	}
+}
+
+// Load the file at path with the load-file builtin of env. the path is passed as a string value rather than read from
+// source so that any path can be loaded
+func loadFile(path string, env *mal.Env) {
+	defer printPanic()
+	fn, err := env.Get("load-file")
+	if err != nil {
+		panic(err)
+	}
+	fn.Val.(func(args ...mal.Value) mal.Value)(mal.Value{Type: mal.KindString, Val: path})
+}
+
+// Read and evaluate every form from reader. recover panics per form so that later forms are still evaluated. Reading
+// stops at a syntax error because the rest of the input cannot be read reliably.
+func evalForms(reader *mal.Reader, env *mal.Env) {
+	for {
+		form, err := reader.ReadForm()
+		if err == io.EOF {
+			return
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%v", r)
+				}
+			}()
+			if err == nil {
+				mal.Eval(form, env)
+			}
+		}()
+		if err != nil {
 			const colorRed, colorReset = "\033[31m", "\033[0m"
-			fmt.Printf("%sError: %s%s", colorRed, r, colorReset)
+			fmt.Fprintf(os.Stderr, "%sError: %s%s\n", colorRed, err, colorReset)
+			var syntaxErr *mal.SyntaxError
+			if errors.As(err, &syntaxErr) && !syntaxErr.Incomplete {
+				return
+			}
 		}
-	}()
-	return mal.Print(mal.Eval(mal.Read(str), env), true)
+	}
 }
 
 // Starts the Mal-arkey REPL. If command line args are provided, the first arg is treated as a file to load, and the
@@ -29,15 +84,20 @@ func main() {
 	// self-hosted fns
 	rep(`(def! not (fn* (a) (if a false true)))`, env)
 	rep(`(defmacro! cond (fn* (& xs) (if (> (count xs) 0) (list 'if (first xs) (if (> (count xs) 1) (nth xs 1) (throw "odd number of forms to cond")) (cons 'cond (rest (rest xs)))))))`, env)
-	rep(`(def! load-file (fn* (f) (eval (read-string (str "(do " (slurp f) "\nnil)")))))`, env)
 
 	if len(os.Args) > 1 {
 		var vals []mal.Value
 		for _, arg := range os.Args[2:] {
-			vals = append(vals, mal.Value{Type: "string", Val: arg})
+			vals = append(vals, mal.Value{Type: mal.KindString, Val: arg})
 		}
-		env.Set("*ARGV*", mal.Value{Type: "list", Val: vals})
-		rep(fmt.Sprintf("(load-file \"%s\")", os.Args[1]), env)
+		env.Set("*ARGV*", mal.Value{Type: mal.KindList, Val: vals})
+		loadFile(os.Args[1], env)
+		return
+	}
+
+	// piped stdin is evaluated form by form like a file, reporting errors per form
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		evalForms(mal.NewEnvReader(env, os.Stdin, "<stdin>"), env)
 		return
 	}
 
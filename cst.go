@@ -0,0 +1,118 @@
+package malarkey
+
+import (
+	"io"
+	"strings"
+)
+
+// Node is a node of a lossless concrete syntax tree. Every node keeps the whitespace, commas and comments before it
+// and the exact source text of its tokens, so printing the tree reproduces the source byte for byte.
+//
+// Token is the atom of a leaf node, the opening delimiter of a collection or the prefix of a reader macro. The
+// children of a collection are its elements and the children of a reader macro are the forms it applies to, e.g. the
+// meta and the form of `^meta form`. Collections and the root also keep the trivia before their closing delimiter (or
+// the end of the input for the root) and the closing token.
+type Node struct {
+	Leading      []Token
+	Token        Token
+	Children     []*Node
+	CloseLeading []Token
+	Close        Token
+}
+
+// IsRoot returns true if the node is the root of a tree returned by ParseCST.
+func (n *Node) IsRoot() bool {
+	return n.Token.Kind == TokenEOF
+}
+
+// String returns the source text of the node and its leading trivia.
+func (n *Node) String() string {
+	var b strings.Builder
+	n.write(&b)
+	return b.String()
+}
+
+func (n *Node) write(b *strings.Builder) {
+	for _, t := range n.Leading {
+		b.WriteString(t.Text)
+	}
+	b.WriteString(n.Token.Text)
+	for _, child := range n.Children {
+		child.write(b)
+	}
+	for _, t := range n.CloseLeading {
+		b.WriteString(t.Text)
+	}
+	b.WriteString(n.Close.Text)
+}
+
+// ParseCST parses source text into a concrete syntax tree. The returned root node has the top-level forms as its
+// children and the trivia at the end of the input as its CloseLeading.
+func ParseCST(r io.Reader, file string) (root *Node, err error) {
+	defer recoverErr(&err)
+	lexer := NewLexer(r, file)
+	lexer.KeepTrivia = true
+	p := &cstParser{reader: &Reader{File: file, lexer: lexer}}
+
+	root = &Node{}
+	for {
+		leading := p.trivia()
+		if p.reader.Peek().Kind == TokenEOF {
+			root.CloseLeading, root.Close = leading, p.reader.Next()
+			return root, nil
+		}
+		root.Children = append(root.Children, p.parseNode(leading))
+	}
+}
+
+type cstParser struct {
+	reader *Reader
+}
+
+// trivia consumes and returns whitespace and comment tokens.
+func (p *cstParser) trivia() []Token {
+	var tokens []Token
+	for {
+		token := p.reader.Peek()
+		if token.Kind != TokenWhitespace && token.Kind != TokenComment {
+			return tokens
+		}
+		tokens = append(tokens, p.reader.Next())
+	}
+}
+
+// parseNode parses the next form after its already consumed leading trivia.
+func (p *cstParser) parseNode(leading []Token) *Node {
+	token := p.reader.Next()
+	node := &Node{Leading: leading, Token: token}
+	switch token.Kind {
+	case TokenEOF:
+		p.reader.incompletef(token.Start, "unexpected end of input at %s", token.Start)
+	case TokenClose:
+		p.reader.errorf(token.Start, "unexpected %s at %s", token.Text, token.Start)
+	case TokenOpen:
+		stopToken := closeTokens[token.Text]
+		for {
+			leading := p.trivia()
+			next := p.reader.Peek()
+			if next.Kind == TokenEOF {
+				p.reader.incompletef(token.Start, "unmatched %s opened at %s", token.Text, token.Start)
+			}
+			if next.Kind == TokenClose && next.Text != stopToken {
+				p.reader.errorf(token.Start, "unmatched %s opened at %s", token.Text, token.Start)
+			}
+			if next.Kind == TokenClose {
+				node.CloseLeading, node.Close = leading, p.reader.Next()
+				return node
+			}
+			node.Children = append(node.Children, p.parseNode(leading))
+		}
+	case TokenMacro:
+		// `^meta form` applies to two forms. every other reader macro applies to one
+		node.Children = append(node.Children, p.parseNode(p.trivia()))
+		if token.Text == "^" {
+			node.Children = append(node.Children, p.parseNode(p.trivia()))
+		}
+	}
+	return node
+}
@@ -0,0 +1,31 @@
+package malarkey_test
+
+import (
+	"strings"
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+func TestParseCSTRoundTrip(t *testing.T) {
+	srcs := []string{
+		"",
+		"  ; only a comment",
+		"(a ,b\n\t c) ; trailing\n",
+		"^{:a 1} [x #_ y 'z `(~a ~@b) @c]",
+		"{:k \"v\" #{1 2}}  #?(:clj 1.5M :default 2)",
+		"(a \xff b) \"x\xfe\xffy\" ; c\xc3\n",
+		`"""raw "quoted" \n""" #"re\d" \a \newline`,
+		"#inst \"2020-01-01T00:00:00Z\" #(+ % %2)\r\n",
+	}
+	for _, src := range srcs {
+		root, err := mal.ParseCST(strings.NewReader(src), "")
+		if err != nil {
+			t.Errorf("ParseCST(%q): %v", src, err)
+			continue
+		}
+		if got := root.String(); got != src {
+			t.Errorf("ParseCST(%q).String() = %q", src, got)
+		}
+	}
+}
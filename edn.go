@@ -0,0 +1,162 @@
+package malarkey
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EDN (https://github.com/edn-format/edn) is the data subset of the reader syntax. Reading EDN never evaluates
+// anything and rejects the reader macros of code: quoting, deref, metadata, #() function literals, reader
+// conditionals, regexes and raw strings. It also rejects the literals that extend EDN: hex, octal, binary, radix and
+// ratio numbers, digit separators, the \b \f \0 and \u{...} string escapes and the \backspace and \formfeed
+// characters. Tagged literals are read with the registered data readers.
+
+// ReadEDN parses the first EDN value of src. Empty input reads as nil.
+func ReadEDN(src string) (v Value, err error) {
+	defer recoverErr(&err)
+	reader := NewReader(strings.NewReader(src), "")
+	reader.EDN = true
+	return readEDN(reader), nil
+}
+
+// readEDN reads the first form of an EDN reader, or nil if it has none.
+func readEDN(reader *Reader) Value {
+	form, err := reader.ReadForm()
+	if err == io.EOF {
+		return Value{Type: KindNil, Val: nil}
+	} else if err != nil {
+		panic(err)
+	}
+	return form
+}
+
+// WriteEDN returns the EDN text of a value. Values with no EDN representation, such as functions, atoms and
+// regexes, are errors.
+func WriteEDN(v Value) (string, error) {
+	var b strings.Builder
+	if err := Fprint(&b, v, PrintOptions{EDN: true}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ednCodeTokens are the reader macros and literals of code that are not EDN.
+var ednCodeTokens = map[string]bool{"'": true, "`": true, "~": true, "~@": true, "@": true, "^": true, "#?": true, "#?@": true, "#(": true}
+
+// EDN numbers are decimal integers, with an optional N suffix for bigints, and floats
+var ednNumberRegex = regexp.MustCompile(`^[+-]?(?:0|[1-9][0-9]*)(?:N|(?:\.[0-9]*)?(?:[eE][+-]?[0-9]+)?)$`)
+
+// EDN named characters
+var ednCharNames = map[string]bool{"newline": true, "return": true, "space": true, "tab": true}
+
+// isEDNToken returns false if a token is valid in code but not in EDN.
+func isEDNToken(token Token) bool {
+	switch token.Kind {
+	case TokenMacro, TokenOpen:
+		return !ednCodeTokens[token.Text]
+	case TokenRegex:
+		return false
+	case TokenString:
+		if strings.HasPrefix(token.Text, `"""`) {
+			return false
+		}
+		for i := 1; i < len(token.Text)-1; i++ {
+			if token.Text[i] != '\\' {
+				continue
+			}
+			i++
+			switch token.Text[i] {
+			case 'b', 'f', '0':
+				return false
+			case 'u':
+				if token.Text[i+1] == '{' {
+					return false
+				}
+			}
+		}
+	case TokenChar:
+		name := token.Text[1:]
+		return utf8.RuneCountInString(name) == 1 || ednCharNames[name] || (len(name) == 5 && name[0] == 'u')
+	case TokenAtom:
+		if _, ok, _ := parseNumber(token.Text); ok {
+			return ednNumberRegex.MatchString(token.Text)
+		}
+	}
+	return true
+}
+
+// ednChar returns the EDN character literal of r. Characters without an EDN name or printable form are \u escaped.
+func ednChar(r rune) string {
+	for name := range ednCharNames {
+		if charNames[name] == r {
+			return `\` + name
+		}
+	}
+	if unicode.IsPrint(r) || r > 0xffff {
+		return `\` + string(r)
+	}
+	return fmt.Sprintf(`\u%04x`, r)
+}
+
+// ednString returns the EDN string literal of s. Unlike escapeString it only uses the escapes of the EDN spec.
+func ednString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			// runes outside the BMP have no \u escape and are written as is
+			if !unicode.IsPrint(r) && r <= 0xffff {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ednFloat returns the EDN literal of a float. It always has a decimal point or exponent so that it reads back as a
+// float.
+func ednFloat(f float64) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("cannot write %v as EDN", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s, nil
+}
+
+// ednReadersOption returns a data reader lookup of an edn/read-string :readers option, a hash-map of tag symbols to
+// functions.
+func ednReadersOption(readers Value) func(tag string) (func(Value) Value, bool) {
+	if readers.Type != KindHashMap {
+		panic("edn/read-string option :readers must be a hash-map")
+	}
+	return func(tag string) (func(Value) Value, bool) {
+		fn, ok := mapGet(readers, Value{Type: KindSymbol, Val: tag})
+		if !ok || getFn(fn) == nil {
+			return nil, false
+		}
+		return func(form Value) Value { return getFn(fn)(form) }, true
+	}
+}
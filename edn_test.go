@@ -0,0 +1,50 @@
+package malarkey_test
+
+import (
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+func TestEDNRoundTrip(t *testing.T) {
+	srcs := []string{
+		`nil`,
+		`{:a [1 2.5 "s\n"] :b #{c/d}}`,
+		`(1 (2 (3)))`,
+		`[\a \newline 12345678901234567890N]`,
+		`#inst "2020-01-01T00:00:00Z"`,
+		`#uuid "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"`,
+	}
+	for _, src := range srcs {
+		v, err := mal.ReadEDN(src)
+		if err != nil {
+			t.Errorf("ReadEDN(%q): %v", src, err)
+			continue
+		}
+		out, err := mal.WriteEDN(v)
+		if err != nil {
+			t.Errorf("WriteEDN(ReadEDN(%q)): %v", src, err)
+			continue
+		}
+		if out != src {
+			t.Errorf("WriteEDN(ReadEDN(%q)) = %q", src, out)
+		}
+	}
+}
+
+func TestReadEDNRejectsCode(t *testing.T) {
+	for _, src := range []string{`'a`, `#(+ % 1)`, `#"re"`, `0x10`, `1/2`, `^:m [1]`, `#?(:clj 1)`, `"\u{e9}"`} {
+		if v, err := mal.ReadEDN(src); err == nil {
+			t.Errorf("ReadEDN(%q) = %s, want an error", src, mal.Print(v, true))
+		}
+	}
+}
+
+func TestWriteEDNRejectsCode(t *testing.T) {
+	for _, src := range []string{`(fn* [] 1)`, `(atom 1)`, `#"re"`} {
+		v := mal.Eval(mal.Read(src), mal.BuiltinEnv())
+		if out, err := mal.WriteEDN(v); err == nil {
+			t.Errorf("WriteEDN(%s) = %q, want an error", src, out)
+		}
+	}
+}
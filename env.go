@@ -3,25 +3,35 @@ package malarkey
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"math/big"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// HostLanguage is the default value of *host-language*.
+const HostLanguage = "Mal-arkey"
+
 // Env is a map of symbols to bound values.
 type Env struct {
 	outer    *Env
 	bindings map[string]Value
+	printers map[string]func(Value, bool) string // printers defined by print-method. only set on a BuiltinEnv
 }
 
 // NewEnv creates a new environment with the given outer environment.
 func NewEnv(outer *Env, bindSymbols []Value, bindValues []Value) *Env {
 	bindings := map[string]Value{}
 	for i, s := range bindSymbols {
-		if s.Type == "symbol" && s.Val.(string) == "&" {
-			bindings[bindSymbols[i+1].Val.(string)] = Value{Type: "list", Val: bindValues[i:]}
+		if s.Type == KindSymbol && s.Str() == "&" {
+			bindings[bindSymbols[i+1].Str()] = Value{Type: KindList, Val: bindValues[i:]}
 			break
 		}
-		bindings[s.Val.(string)] = bindValues[i]
+		bindings[s.Str()] = bindValues[i]
 	}
 
 	return &Env{
@@ -46,6 +56,18 @@ func (e *Env) Get(symbol string) (Value, error) {
 	return Value{}, fmt.Errorf("'%v' not found", symbol)
 }
 
+// Print returns a string representation of a value like the printing builtins of env: with the printers defined by
+// print-method and limited by *print-length* and *print-level*. Go's Print only uses the printers of RegisterPrinter.
+func (e *Env) Print(v Value, readably bool) string {
+	return Sprint(v, envPrintOptions(e, readably))
+}
+
+// validateArgs spec matching any number
+const numberArgs = integerArg | floatArg | bigIntArg | ratioArg
+
+// validateArgs spec matching the sequences of seqElems
+const seqArgs = listArg | vectorArg | stringArg
+
 // hack for numerical comparison
 func asFloat(v interface{}) float64 {
 	switch v := v.(type) {
@@ -53,6 +75,12 @@ func asFloat(v interface{}) float64 {
 		return float64(v)
 	case float64:
 		return v
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
 	default:
 		panic(fmt.Sprintf("cannot convert %v to float64", v))
 	}
@@ -60,394 +88,837 @@ func asFloat(v interface{}) float64 {
 
 // get the underlying go function from a function Value
 func getFn(expr Value) func(...Value) Value {
-	if expr.Type == "function" {
+	if expr.Type == KindFunction {
 		return expr.Val.(func(...Value) Value)
-	} else if expr.Type == "function-tco" {
+	} else if expr.Type == KindFunctionTCO {
 		return expr.Val.(FunctionTCO).Fn
 	}
 	return nil
 }
 
+// NewEnvReader creates a Reader whose reader conditional features follow *host-language* in env and whose data
+// readers include the functions of *data-readers* in env, a hash-map of tag symbols (or strings) to functions.
+// *data-readers* is looked up as each tagged literal is read, so that it can be redefined while reading a file.
+func NewEnvReader(env *Env, r io.Reader, file string) *Reader {
+	reader := NewReader(r, file)
+	if lang, err := env.Get("*host-language*"); err == nil && lang.Type == KindString {
+		reader.Features = []string{hostFeature(lang.Str())}
+	}
+	reader.DataReader = func(tag string) (func(Value) Value, bool) {
+		readers, err := env.Get("*data-readers*")
+		if err != nil || readers.Type != KindHashMap {
+			return nil, false
+		}
+		fn, ok := mapGet(readers, Value{Type: KindSymbol, Val: tag})
+		if !ok {
+			fn, _ = mapGet(readers, Value{Type: KindString, Val: tag})
+		}
+		if getFn(fn) == nil {
+			return nil, false
+		}
+		return func(form Value) Value { return getFn(fn)(form) }, true
+	}
+	return reader
+}
+
+// envPrintOptions returns print options limited by *print-length* and *print-level* in env. nil is unlimited.
+func envPrintOptions(env *Env, readably bool) PrintOptions {
+	opts := PrintOptions{Readably: readably}
+	for e := env; e != nil; e = e.outer {
+		if e.printers != nil {
+			opts.Printers = e.printers
+			break
+		}
+	}
+	for name, limit := range map[string]*int{"*print-length*": &opts.MaxLength, "*print-level*": &opts.MaxLevel} {
+		v, err := env.Get(name)
+		if err != nil || v.Type == KindNil {
+			continue
+		}
+		if v.Type != KindInteger || v.Int() <= 0 {
+			panic(fmt.Sprintf("%s must be nil or a positive integer", name))
+		}
+		*limit = int(v.Int())
+	}
+	return opts
+}
+
+// fprintln streams space-separated values and a newline to w.
+func fprintln(w io.Writer, args []Value, opts PrintOptions) {
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		if err := Fprint(w, arg, opts); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
 // BuiltinEnv creates a new default built-in function env.
 func BuiltinEnv() *Env {
+	atoms := &atomCounter{}
 	env := &Env{
-		outer: nil,
+		outer:    nil,
+		printers: map[string]func(Value, bool) string{},
 		bindings: map[string]Value{
-			"*host-language*": {Type: "string", Val: "Mal-arkey"},
-			"+": {Type: "function", Val: func(args ...Value) Value {
-				var sum int64
+			"*host-language*": {Type: KindString, Val: HostLanguage},
+			"*data-readers*":  {Type: KindHashMap, Val: map[string]MapEntry{}},
+			"*print-length*":  {Type: KindNil, Val: nil},
+			"*print-level*":   {Type: KindNil, Val: nil},
+			"+": {Type: KindFunction, Val: func(args ...Value) Value {
+				sum := Value{Type: KindInteger, Val: int64(0)}
 				for _, arg := range args {
-					sum += arg.Val.(int64)
+					sum = arith("+", sum, arg)
 				}
-				return Value{Type: "integer", Val: sum}
+				return sum
 			}},
-			"-": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("-", args, []string{"integer", "integer", "*"})
-				var diff int64
-				for i, arg := range args {
-					if i == 0 {
-						diff = arg.Val.(int64)
-					} else {
-						diff -= arg.Val.(int64)
-					}
+			"-": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("-", args, []kindSet{numberArgs, numberArgs, moreArgs})
+				diff := args[0]
+				for _, arg := range args[1:] {
+					diff = arith("-", diff, arg)
 				}
-				return Value{Type: "integer", Val: diff}
+				return diff
 			}},
-			"*": {Type: "function", Val: func(args ...Value) Value {
-				var product int64 = 1
+			"*": {Type: KindFunction, Val: func(args ...Value) Value {
+				product := Value{Type: KindInteger, Val: int64(1)}
 				for _, arg := range args {
-					product *= arg.Val.(int64)
+					product = arith("*", product, arg)
 				}
-				return Value{Type: "integer", Val: product}
+				return product
 			}},
-			"/": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("/", args, []string{"integer", "*"})
-				var quotient int64 = 1
+			"/": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("/", args, []kindSet{numberArgs, moreArgs})
+				quotient := Value{Type: KindInteger, Val: int64(1)}
 				for i, arg := range args {
 					if i == 0 && len(args) > 1 {
-						quotient = arg.Val.(int64)
+						quotient = arg
 					} else {
-						quotient /= arg.Val.(int64)
+						quotient = arith("/", quotient, arg)
 					}
 				}
-				return Value{Type: "integer", Val: quotient}
+				return quotient
 			}},
-			"pr-str": {Type: "function", Val: func(args ...Value) Value {
-				var strs []string
-				for _, arg := range args {
-					strs = append(strs, Print(arg, true))
-				}
-				return Value{Type: "string", Val: strings.Join(strs, " ")}
+			"list": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindList, Val: args}
 			}},
-			"str": {Type: "function", Val: func(args ...Value) Value {
-				var strs []string
-				for _, arg := range args {
-					strs = append(strs, Print(arg, false))
-				}
-				return Value{Type: "string", Val: strings.Join(strs, "")}
+			"list?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindList}
 			}},
-			"prn": {Type: "function", Val: func(args ...Value) Value {
-				var strs []string
-				for _, arg := range args {
-					strs = append(strs, Print(arg, true))
+			"empty?": {Type: KindFunction, Val: func(args ...Value) Value {
+				if len(args) > 0 && args[0].Type == KindSet {
+					return Value{Type: KindBoolean, Val: len(args[0].Val.(map[string]Value)) == 0}
 				}
-				fmt.Println(strings.Join(strs, " "))
-				return Value{Type: "nil", Val: nil}
-			}},
-			"println": {Type: "function", Val: func(args ...Value) Value {
-				var strs []string
-				for _, arg := range args {
-					strs = append(strs, Print(arg, false))
+				if len(args) > 0 && args[0].Type == KindHashMap {
+					return Value{Type: KindBoolean, Val: len(args[0].Val.(map[string]MapEntry)) == 0}
 				}
-				fmt.Println(strings.Join(strs, " "))
-				return Value{Type: "nil", Val: nil}
-			}},
-			"list": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "list", Val: args}
-			}},
-			"list?": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "boolean", Val: len(args) > 0 && args[0].Type == "list"}
-			}},
-			"empty?": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "boolean", Val: len(args) > 0 && args[0].Type == "list" && len(args[0].Val.([]Value)) == 0}
-			}},
-			"count": {Type: "function", Val: func(args ...Value) Value {
-				if len(args) > 0 && args[0].Type == "list" {
-					return Value{Type: "integer", Val: int64(len(args[0].Val.([]Value)))}
+				if len(args) > 0 && args[0].Type == KindString {
+					return Value{Type: KindBoolean, Val: args[0].Str() == ""}
 				}
-				return Value{Type: "integer", Val: int64(0)}
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindList && len(args[0].Elems()) == 0}
 			}},
-			"=": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("=", args, []string{"any", "any"})
-				if args[0].Type == "list" && args[1].Type == "list" {
-					alist := args[0].Val.([]Value)
-					blist := args[1].Val.([]Value)
-					if len(alist) != len(blist) {
-						return Value{Type: "boolean", Val: false}
-					}
-					for i, a := range alist {
-						if a.Type != blist[i].Type || a.Val != blist[i].Val {
-							return Value{Type: "boolean", Val: false}
-						}
-					}
-					return Value{Type: "boolean", Val: true}
+			"count": {Type: KindFunction, Val: func(args ...Value) Value {
+				if len(args) > 0 && args[0].Type == KindList {
+					return Value{Type: KindInteger, Val: int64(len(args[0].Elems()))}
 				}
-
-				return Value{Type: "boolean", Val: args[0].Type == args[1].Type && args[0].Val == args[1].Val}
+				if len(args) > 0 && args[0].Type == KindSet {
+					return Value{Type: KindInteger, Val: int64(len(args[0].Val.(map[string]Value)))}
+				}
+				if len(args) > 0 && args[0].Type == KindHashMap {
+					return Value{Type: KindInteger, Val: int64(len(args[0].Val.(map[string]MapEntry)))}
+				}
+				if len(args) > 0 && args[0].Type == KindString {
+					return Value{Type: KindInteger, Val: int64(utf8.RuneCountInString(args[0].Str()))}
+				}
+				return Value{Type: KindInteger, Val: int64(0)}
 			}},
-			"<": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("<", args, []string{"any", "any"})
-				return Value{Type: "boolean", Val: asFloat(args[0].Val) < asFloat(args[1].Val)}
+			"=": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("=", args, []kindSet{anyArg, anyArg})
+				return Value{Type: KindBoolean, Val: equal(args[0], args[1])}
 			}},
-			"<=": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("<=", args, []string{"any", "any"})
-				return Value{Type: "boolean", Val: asFloat(args[0].Val) <= asFloat(args[1].Val)}
+			"<": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("<", args, []kindSet{numberArgs, numberArgs})
+				return Value{Type: KindBoolean, Val: compareNumbers(args[0], args[1]) < 0}
 			}},
-			">": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs(">", args, []string{"any", "any"})
-				return Value{Type: "boolean", Val: asFloat(args[0].Val) > asFloat(args[1].Val)}
+			"<=": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("<=", args, []kindSet{numberArgs, numberArgs})
+				return Value{Type: KindBoolean, Val: compareNumbers(args[0], args[1]) <= 0}
 			}},
-			">=": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs(">=", args, []string{"any", "any"})
-				return Value{Type: "boolean", Val: asFloat(args[0].Val) >= asFloat(args[1].Val)}
+			">": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs(">", args, []kindSet{numberArgs, numberArgs})
+				return Value{Type: KindBoolean, Val: compareNumbers(args[0], args[1]) > 0}
 			}},
-			"read-string": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("read-string", args, []string{"string"})
-				return Read(args[0].Val.(string))
+			">=": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs(">=", args, []kindSet{numberArgs, numberArgs})
+				return Value{Type: KindBoolean, Val: compareNumbers(args[0], args[1]) >= 0}
 			}},
-			"slurp": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("slurp", args, []string{"string"})
-				s, err := os.ReadFile(args[0].Val.(string))
+			"slurp": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("slurp", args, []kindSet{stringArg})
+				s, err := os.ReadFile(args[0].Str())
 				if err != nil {
 					panic(fmt.Sprintf("error reading file: %v", err))
 				}
-				return Value{Type: "string", Val: string(s)}
+				return Value{Type: KindString, Val: string(s)}
 			}},
-			"atom": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("atom", args, []string{"any"})
-				atoms = append(atoms, args[0])
-				return Value{Type: "atom", Val: len(atoms) - 1}
+			"atom": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("atom", args, []kindSet{anyArg})
+				return atoms.newAtom(args[0])
 			}},
-			"atom?": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "boolean", Val: len(args) > 0 && args[0].Type == "atom"}
+			"atom?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindAtom}
 			}},
-			"deref": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("deref", args, []string{"atom"})
-				return atoms[args[0].Val.(int)]
+			"deref": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("deref", args, []kindSet{atomArg})
+				return args[0].Val.(*Atom).Val
 			}},
-			"reset!": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("reset!", args, []string{"atom", "any"})
-				atoms[args[0].Val.(int)] = args[1]
+			"reset!": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("reset!", args, []kindSet{atomArg, anyArg})
+				args[0].Val.(*Atom).Val = args[1]
 				return args[1]
 			}},
-			"swap!": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("swap!", args, []string{"atom", "any", "*"})
+			"swap!": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("swap!", args, []kindSet{atomArg, anyArg, moreArgs})
 				fn := getFn(args[1])
 				if fn == nil {
 					panic("second argument to `swap!` must be a function")
 				}
 
-				atomID := args[0].Val.(int)
-				atomCur := atoms[atomID]
+				atom := args[0].Val.(*Atom)
 
-				fnArgs := append([]Value{atomCur}, args[2:]...)
+				fnArgs := append([]Value{atom.Val}, args[2:]...)
 				val := fn(fnArgs...)
 
-				atoms[atomID] = val
+				atom.Val = val
 				return val
 			}},
-			"cons": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("cons", args, []string{"any", "list"})
-				return Value{Type: "list", Val: append([]Value{args[0]}, args[1].Val.([]Value)...)}
+			"cons": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("cons", args, []kindSet{anyArg, seqArgs})
+				return Value{Type: KindList, Val: append([]Value{args[0]}, seqElems(args[1])...)}
 			}},
-			"concat": {Type: "function", Val: func(args ...Value) Value {
+			"concat": {Type: KindFunction, Val: func(args ...Value) Value {
 				var vals []Value
 				for _, arg := range args {
-					if arg.Type != "list" {
-						panic("all arguments to `concat` must be lists")
+					if !seqArgs.has(arg.Type) {
+						panic("all arguments to `concat` must be lists, vectors or strings")
 					}
-					vals = append(vals, arg.Val.([]Value)...)
+					vals = append(vals, seqElems(arg)...)
 				}
-				return Value{Type: "list", Val: vals}
+				return Value{Type: KindList, Val: vals}
 			}},
-			"nth": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("nth", args, []string{"list|vector", "integer"})
-				list := args[0].Val.([]Value)
-				idx := args[1].Val.(int64)
+			"nth": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("nth", args, []kindSet{seqArgs, integerArg})
+				list := seqElems(args[0])
+				idx := args[1].Int()
 				if idx < 0 || idx >= int64(len(list)) {
 					panic("index out of bounds")
 				}
 				return list[idx]
 			}},
-			"first": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("first", args, []string{"nil|list|vector"})
-				if args[0].Type == "nil" {
-					return Value{Type: "nil", Val: nil}
+			"first": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("first", args, []kindSet{nilArg | listArg | vectorArg | stringArg})
+				if args[0].Type == KindNil || args[0].Val == "" {
+					return Value{Type: KindNil, Val: nil}
+				}
+				if args[0].Type == KindString {
+					r, _ := utf8.DecodeRuneInString(args[0].Str())
+					return Value{Type: KindChar, Val: r}
 				}
-				list := args[0].Val.([]Value)
+				list := args[0].Elems()
 				if len(list) == 0 {
-					return Value{Type: "nil", Val: nil}
+					return Value{Type: KindNil, Val: nil}
 				}
 				return list[0]
 			}},
-			"rest": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("rest", args, []string{"nil|list|vector"})
-				if args[0].Type == "nil" {
-					return Value{Type: "list", Val: []Value{}}
+			"rest": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("rest", args, []kindSet{nilArg | seqArgs})
+				if args[0].Type == KindNil {
+					return Value{Type: KindList, Val: []Value{}}
 				}
-				list := args[0].Val.([]Value)
+				list := seqElems(args[0])
 				if len(list) == 0 {
-					return Value{Type: "list", Val: []Value{}}
+					return Value{Type: KindList, Val: []Value{}}
 				}
-				return Value{Type: "list", Val: list[1:]}
+				return Value{Type: KindList, Val: list[1:]}
 			}},
-			"throw": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("throw", args, []string{"any"})
+			"throw": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("throw", args, []kindSet{anyArg})
 				panic(args[0])
 			}},
-			"apply": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("apply", args, []string{"function|function-tco", "any", "*"})
-				if args[len(args)-1].Type != "list" {
-					panic("last argument to `apply` must be a list")
+			"apply": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("apply", args, []kindSet{functionArg | functionTCOArg, anyArg, moreArgs})
+				if !seqArgs.has(args[len(args)-1].Type) {
+					panic("last argument to `apply` must be a list, vector or string")
 				}
 
 				fn := getFn(args[0])
 				if fn == nil {
 					panic("first argument to `apply` must be a function")
 				}
-				fnArgs := append(args[1:len(args)-1], args[len(args)-1].Val.([]Value)...)
+				fnArgs := append(args[1:len(args)-1], seqElems(args[len(args)-1])...)
 				return fn(fnArgs...)
 			}},
-			"map": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("map", args, []string{"function|function-tco", "list|vector"})
+			"map": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("map", args, []kindSet{functionArg | functionTCOArg, seqArgs})
 				fn := getFn(args[0])
 				if fn == nil {
 					panic("first argument to `map` must be a function")
 				}
 
 				var res []Value
-				for _, arg := range args[1].Val.([]Value) {
+				for _, arg := range seqElems(args[1]) {
 					res = append(res, fn(arg))
 				}
-				return Value{Type: "list", Val: res}
+				return Value{Type: KindList, Val: res}
 			}},
-			"nil?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("nil?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "nil"}
+			"nil?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("nil?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindNil}
 			}},
-			"true?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("true?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "boolean" && args[0].Val.(bool)}
+			"true?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("true?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindBoolean && args[0].Bool()}
 			}},
-			"false?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("false?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "boolean" && !args[0].Val.(bool)}
+			"false?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("false?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindBoolean && !args[0].Bool()}
 			}},
-			"symbol": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("symbol", args, []string{"string"})
-				return Value{Type: "symbol", Val: args[0].Val.(string)}
+			"symbol": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("symbol", args, []kindSet{stringArg})
+				return Value{Type: KindSymbol, Val: args[0].Str()}
 			}},
-			"symbol?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("symbol?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "symbol"}
+			"symbol?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("symbol?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindSymbol}
 			}},
-			"keyword": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("keyword", args, []string{"string|keyword"})
-				if args[0].Type == "string" {
-					return Value{Type: "keyword", Val: ":" + args[0].Val.(string)}
+			"keyword": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("keyword", args, []kindSet{stringArg | keywordArg})
+				if args[0].Type == KindString {
+					return Value{Type: KindKeyword, Val: ":" + args[0].Str()}
 				}
 				return args[0]
 			}},
-			"keyword?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("keyword?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "keyword"}
+			"keyword?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("keyword?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindKeyword}
 			}},
-			"sequential?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("sequential?", args, []string{"any"})
-				return Value{Type: "boolean", Val: args[0].Type == "list" || args[0].Type == "vector"}
+			"sequential?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("sequential?", args, []kindSet{anyArg})
+				return Value{Type: KindBoolean, Val: args[0].Type == KindList || args[0].Type == KindVector}
 			}},
-			"vec": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("vec", args, []string{"list|vector"})
-				return Value{Type: "vector", Val: args[0].Val.([]Value)}
+			"vec": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("vec", args, []kindSet{seqArgs})
+				return Value{Type: KindVector, Val: seqElems(args[0])}
 			}},
-			"vector": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "vector", Val: args}
+			"vector": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindVector, Val: args}
 			}},
-			"vector?": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "boolean", Val: len(args) > 0 && args[0].Type == "vector"}
+			"vector?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindVector}
 			}},
-			"hash-map": {Type: "function", Val: func(args ...Value) Value {
+			"hash-map": {Type: KindFunction, Val: func(args ...Value) Value {
 				if len(args)%2 != 0 {
 					panic("wrong number of arguments. `hash-map` requires an even number of arguments")
 				}
-				kv := map[string]Value{}
-				for i := 0; i < len(args); i += 2 {
-					kv[args[i].Val.(string)] = args[i+1]
-				}
-
-				return Value{Type: "hash-map", Val: kv}
+				return newHashMap(args)
 			}},
-			"map?": {Type: "function", Val: func(args ...Value) Value {
-				return Value{Type: "boolean", Val: len(args) > 0 && args[0].Type == "hash-map"}
+			"map?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindHashMap}
 			}},
-			"assoc": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("assoc", args, []string{"hash-map", "any", "any", "*"})
-				kv := args[0].Val.(map[string]Value)
+			"assoc": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("assoc", args, []kindSet{hashMapArg, anyArg, anyArg, moreArgs})
+				if len(args)%2 != 1 {
+					panic("assoc requires a value for every key")
+				}
+				kv := copyHashMap(args[0])
 				for i := 1; i < len(args)-1; i += 2 {
-					kv[args[i].Val.(string)] = args[i+1]
+					kv[hashKey(args[i])] = MapEntry{Key: args[i], Val: args[i+1]}
 				}
-				return Value{Type: "hash-map", Val: kv}
+				return Value{Type: KindHashMap, Val: kv, Meta: args[0].Meta}
 			}},
-			"dissoc": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("dissoc", args, []string{"hash-map", "list"})
-				kv := args[0].Val.(map[string]Value)
-				for _, arg := range args[1].Val.([]Value) {
-					delete(kv, arg.Val.(string))
+			"dissoc": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("dissoc", args, []kindSet{hashMapArg, moreArgs})
+				kv := copyHashMap(args[0])
+				for _, arg := range args[1:] {
+					delete(kv, hashKey(arg))
 				}
-				return Value{Type: "hash-map", Val: kv}
+				return Value{Type: KindHashMap, Val: kv, Meta: args[0].Meta}
 			}},
-			"keys": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("keys", args, []string{"hash-map"})
+			"keys": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("keys", args, []kindSet{hashMapArg})
 				var keys []Value
-				for k := range args[0].Val.(map[string]Value) {
-					keys = append(keys, Value{Type: "string", Val: k})
+				for _, entry := range mapEntries(args[0]) {
+					keys = append(keys, entry.Key)
 				}
-				return Value{Type: "list", Val: keys}
+				return Value{Type: KindList, Val: keys}
 			}},
-			"vals": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("vals", args, []string{"hash-map"})
+			"vals": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("vals", args, []kindSet{hashMapArg})
 				var values []Value
-				for _, v := range args[0].Val.(map[string]Value) {
-					values = append(values, v)
+				for _, entry := range mapEntries(args[0]) {
+					values = append(values, entry.Val)
+				}
+				return Value{Type: KindList, Val: values}
+			}},
+			"get": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("get", args, []kindSet{hashMapArg | setArg | taggedLiteralArg, anyArg})
+				if args[0].Type == KindTaggedLiteral {
+					// (:tag tl) and (:form tl) in Clojure
+					tl := args[0].Val.(TaggedLiteral)
+					switch {
+					case args[1].Type == KindKeyword && args[1].Val == ":tag":
+						return Value{Type: KindSymbol, Val: tl.Tag}
+					case args[1].Type == KindKeyword && args[1].Val == ":form":
+						return tl.Form
+					}
+					return Value{Type: KindNil, Val: nil}
+				}
+				if args[0].Type == KindSet {
+					if elem, ok := args[0].Val.(map[string]Value)[hashKey(args[1])]; ok {
+						return elem
+					}
+					return Value{Type: KindNil, Val: nil}
+				}
+				if val, ok := mapGet(args[0], args[1]); ok {
+					return val
+				}
+				return Value{Type: KindNil, Val: nil}
+			}},
+			"contains?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("contains?", args, []kindSet{hashMapArg | setArg, anyArg})
+				if args[0].Type == KindSet {
+					_, ok := args[0].Val.(map[string]Value)[hashKey(args[1])]
+					return Value{Type: KindBoolean, Val: ok}
+				}
+				_, ok := mapGet(args[0], args[1])
+				return Value{Type: KindBoolean, Val: ok}
+			}},
+			"char": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("char", args, []kindSet{integerArg | charArg})
+				if args[0].Type == KindChar {
+					return args[0]
+				}
+				code := args[0].Int()
+				if code < 0 || code > unicode.MaxRune {
+					panic(fmt.Sprintf("value out of range for char: %d", code))
+				}
+				return Value{Type: KindChar, Val: rune(code)}
+			}},
+			"number?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && isNumber(args[0])}
+			}},
+			"bigint": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("bigint", args, []kindSet{numberArgs})
+				switch args[0].Type {
+				case KindInteger, KindBigInt:
+					return Value{Type: KindBigInt, Val: toBigInt(args[0])}
+				case KindRatio:
+					return Value{Type: KindBigInt, Val: new(big.Int).Quo(args[0].Val.(*big.Rat).Num(), args[0].Val.(*big.Rat).Denom())}
+				}
+				i, _ := big.NewFloat(args[0].Float()).Int(nil)
+				return Value{Type: KindBigInt, Val: i}
+			}},
+			"ratio?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindRatio}
+			}},
+			"char?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindChar}
+			}},
+			"int": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("int", args, []kindSet{integerArg | floatArg | charArg})
+				switch v := args[0].Val.(type) {
+				case rune:
+					return Value{Type: KindInteger, Val: int64(v)}
+				case float64:
+					return Value{Type: KindInteger, Val: int64(v)}
+				}
+				return args[0]
+			}},
+			"seq": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("seq", args, []kindSet{nilArg | listArg | vectorArg | stringArg | setArg})
+				var elems []Value
+				switch args[0].Type {
+				case KindList, KindVector:
+					elems = args[0].Elems()
+				case KindString:
+					elems = stringChars(args[0].Str())
+				case KindSet:
+					elems = setElems(args[0])
 				}
-				return Value{Type: "list", Val: values}
+				if len(elems) == 0 {
+					return Value{Type: KindNil, Val: nil}
+				}
+				return Value{Type: KindList, Val: elems}
+			}},
+			"regex?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindRegex}
+			}},
+			"re-pattern": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("re-pattern", args, []kindSet{stringArg | regexArg})
+				if args[0].Type == KindRegex {
+					return args[0]
+				}
+				re, err := regexp.Compile(args[0].Str())
+				if err != nil {
+					panic(fmt.Sprintf("invalid regex: %v", err))
+				}
+				return Value{Type: KindRegex, Val: re}
+			}},
+			"re-find": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("re-find", args, []kindSet{regexArg, stringArg})
+				re, s := args[0].Val.(*regexp.Regexp), args[1].Str()
+				loc := re.FindStringSubmatchIndex(s)
+				if loc == nil {
+					return Value{Type: KindNil, Val: nil}
+				}
+				return matchValue(re, s, loc)
+			}},
+			"re-matches": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("re-matches", args, []kindSet{regexArg, stringArg})
+				re, s := args[0].Val.(*regexp.Regexp), args[1].Str()
+				loc := anchored(re).FindStringSubmatchIndex(s)
+				if loc == nil {
+					return Value{Type: KindNil, Val: nil}
+				}
+				return matchValue(re, s, loc)
+			}},
+			"re-seq": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("re-seq", args, []kindSet{regexArg, stringArg})
+				re, s := args[0].Val.(*regexp.Regexp), args[1].Str()
+				var matches []Value
+				for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+					matches = append(matches, matchValue(re, s, loc))
+				}
+				if len(matches) == 0 {
+					return Value{Type: KindNil, Val: nil}
+				}
+				return Value{Type: KindList, Val: matches}
+			}},
+			"re-groups": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("re-groups", args, []kindSet{regexArg, stringArg})
+				re, s := args[0].Val.(*regexp.Regexp), args[1].Str()
+				loc := re.FindStringSubmatchIndex(s)
+				if loc == nil {
+					return Value{Type: KindNil, Val: nil}
+				}
+				return matchGroups(s, loc)
+			}},
+			"replace": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("replace", args, []kindSet{stringArg, stringArg | regexArg, stringArg | functionArg | functionTCOArg})
+				s := args[0].Str()
+				if args[1].Type == KindString {
+					if args[2].Type != KindString {
+						panic("replace requires a string replacement for a string match")
+					}
+					return Value{Type: KindString, Val: strings.ReplaceAll(s, args[1].Str(), args[2].Str())}
+				}
+				return Value{Type: KindString, Val: replaceRegex(s, args[1].Val.(*regexp.Regexp), args[2])}
+			}},
+			"tagged-literal": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("tagged-literal", args, []kindSet{symbolArg, anyArg})
+				return Value{Type: KindTaggedLiteral, Val: TaggedLiteral{Tag: args[0].Str(), Form: args[1]}}
+			}},
+			"tagged-literal?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindTaggedLiteral}
+			}},
+			"inst?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindInst}
 			}},
-			"get": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("get", args, []string{"hash-map", "any"})
-				kv := args[0].Val.(map[string]Value)
-				for i := 1; i < len(args); i++ {
-					if val, ok := kv[args[i].Val.(string)]; ok {
-						return val
+			"uuid?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindUUID}
+			}},
+			"set": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("set", args, []kindSet{nilArg | seqArgs | setArg})
+				switch args[0].Type {
+				case KindNil:
+					return newSet(nil)
+				case KindSet:
+					return Value{Type: KindSet, Val: args[0].Val}
+				}
+				return newSet(seqElems(args[0]))
+			}},
+			"set?": {Type: KindFunction, Val: func(args ...Value) Value {
+				return Value{Type: KindBoolean, Val: len(args) > 0 && args[0].Type == KindSet}
+			}},
+			"conj": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("conj", args, []kindSet{nilArg | listArg | vectorArg | setArg, moreArgs})
+				switch args[0].Type {
+				case KindNil, KindList:
+					var list []Value
+					if args[0].Type == KindList {
+						list = args[0].Elems()
+					}
+					var out []Value
+					for i := len(args) - 1; i > 0; i-- {
+						out = append(out, args[i])
+					}
+					return Value{Type: KindList, Val: append(out, list...)}
+				case KindVector:
+					list := args[0].Elems()
+					return Value{Type: KindVector, Val: append(append([]Value{}, list...), args[1:]...)}
+				default:
+					set := copySet(args[0])
+					for _, arg := range args[1:] {
+						set[hashKey(arg)] = arg
+					}
+					return Value{Type: KindSet, Val: set}
+				}
+			}},
+			"disj": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("disj", args, []kindSet{setArg, moreArgs})
+				set := copySet(args[0])
+				for _, arg := range args[1:] {
+					delete(set, hashKey(arg))
+				}
+				return Value{Type: KindSet, Val: set}
+			}},
+			"set/union": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateSets("set/union", args, 0)
+				return setUnion(args...)
+			}},
+			"set/intersection": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateSets("set/intersection", args, 1)
+				return setIntersection(args[0], args[1:]...)
+			}},
+			"set/difference": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateSets("set/difference", args, 1)
+				return setDifference(args[0], args[1:]...)
+			}},
+			"set/subset?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("set/subset?", args, []kindSet{setArg, setArg})
+				return Value{Type: KindBoolean, Val: isSubset(args[0], args[1])}
+			}},
+			"set/superset?": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("set/superset?", args, []kindSet{setArg, setArg})
+				return Value{Type: KindBoolean, Val: isSubset(args[1], args[0])}
+			}},
+			"edn/read-string": {Type: KindFunction, Val: func(args ...Value) Value {
+				// (edn/read-string s) or (edn/read-string opts s). opts may have :readers, a hash-map of tag symbols to
+				// data reader functions, and the read limits of read-string
+				var opts Value
+				if len(args) == 2 {
+					validateArgs("edn/read-string", args, []kindSet{hashMapArg, stringArg})
+					opts, args = args[0], args[1:]
+				} else {
+					validateArgs("edn/read-string", args, []kindSet{stringArg})
+				}
+				reader := NewReader(strings.NewReader(args[0].Str()), "")
+				reader.EDN = true
+				if opts.Type == KindHashMap {
+					reader.Limits = limitsOption(opts)
+					if readers, ok := mapGet(opts, Value{Type: KindKeyword, Val: ":readers"}); ok {
+						reader.DataReader = ednReadersOption(readers)
 					}
 				}
-				return Value{Type: "nil", Val: nil}
+				return readEDN(reader)
 			}},
-			"contains?": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("contains?", args, []string{"hash-map", "any"})
-				kv := args[0].Val.(map[string]Value)
-				_, ok := kv[args[1].Val.(string)]
-				return Value{Type: "boolean", Val: ok}
+			"edn/write-string": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("edn/write-string", args, []kindSet{anyArg})
+				s, err := WriteEDN(args[0])
+				if err != nil {
+					panic(err)
+				}
+				return Value{Type: KindString, Val: s}
+			}},
+			"json/parse": {Type: KindFunction, Val: func(args ...Value) Value {
+				// (json/parse s) or (json/parse s {:keywordize true}) to read object keys as keywords
+				var keywordize bool
+				if len(args) == 2 {
+					validateArgs("json/parse", args, []kindSet{stringArg, hashMapArg})
+					opt, _ := mapGet(args[1], Value{Type: KindKeyword, Val: ":keywordize"})
+					keywordize = opt.Type == KindBoolean && opt.Bool()
+				} else {
+					validateArgs("json/parse", args, []kindSet{stringArg})
+				}
+				v, err := ParseJSON(args[0].Str(), keywordize)
+				if err != nil {
+					panic(err)
+				}
+				return v
+			}},
+			"json/write": {Type: KindFunction, Val: func(args ...Value) Value {
+				// (json/write v) is compact. (json/write v {:pretty true}) is indented by 2 spaces
+				var indent string
+				if len(args) == 2 {
+					validateArgs("json/write", args, []kindSet{anyArg, hashMapArg})
+					if opt, _ := mapGet(args[1], Value{Type: KindKeyword, Val: ":pretty"}); opt.Type == KindBoolean && opt.Bool() {
+						indent = "  "
+					}
+				} else {
+					validateArgs("json/write", args, []kindSet{anyArg})
+				}
+				s, err := WriteJSON(args[0], indent)
+				if err != nil {
+					panic(err)
+				}
+				return Value{Type: KindString, Val: s}
 			}},
-			"readline": {Type: "function", Val: func(args ...Value) Value {
-				validateArgs("readline", args, []string{"string"})
+			"readline": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("readline", args, []kindSet{stringArg})
 				reader := bufio.NewReader(os.Stdin)
 
-				fmt.Print(args[0].Val.(string))
+				fmt.Print(args[0].Str())
 				input, err := reader.ReadString('\n')
 				if err != nil {
 					if err.Error() == "EOF" {
-						return Value{Type: "nil", Val: nil}
+						return Value{Type: KindNil, Val: nil}
 					}
 					panic(err)
 				}
-				return Value{Type: "string", Val: strings.TrimRight(input, "\n")}
+				return Value{Type: KindString, Val: strings.TrimRight(input, "\n")}
+			}},
+			"meta": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("meta", args, []kindSet{anyArg})
+				return getMeta(args[0])
+			}},
+			"with-meta": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("with-meta", args, []kindSet{listArg | vectorArg | hashMapArg | setArg | symbolArg | functionArg | functionTCOArg, hashMapArg | nilArg})
+				return withMeta(args[0], args[1])
+			}},
+			"vary-meta": {Type: KindFunction, Val: func(args ...Value) Value {
+				validateArgs("vary-meta", args, []kindSet{listArg | vectorArg | hashMapArg | setArg | symbolArg | functionArg | functionTCOArg, functionArg | functionTCOArg, moreArgs})
+				fn := getFn(args[1])
+				fnArgs := append([]Value{getMeta(args[0])}, args[2:]...)
+				return withMeta(args[0], fn(fnArgs...))
 			}},
-			"time-ms":   {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"meta":      {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"with-meta": {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"fn?":       {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"string?":   {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"number?":   {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"seq":       {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
-			"conj":      {Type: "function", Val: func(args ...Value) Value { panic("unimplemented") }},
+			"time-ms": {Type: KindFunction, Val: func(args ...Value) Value { panic("unimplemented") }},
+			"fn?":     {Type: KindFunction, Val: func(args ...Value) Value { panic("unimplemented") }},
+			"string?": {Type: KindFunction, Val: func(args ...Value) Value { panic("unimplemented") }},
 		},
 	}
 
 	// defined here to allow cyclic reference to env
-	env.bindings["eval"] = Value{Type: "function", Val: func(args ...Value) Value {
-		validateArgs("eval", args, []string{"any"})
+	env.bindings["eval"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		validateArgs("eval", args, []kindSet{anyArg})
 		return Eval(args[0], env)
 	}}
+	// the printing functions honor *print-length* and *print-level* in env
+	env.bindings["pr-str"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		opts := envPrintOptions(env, true)
+		var strs []string
+		for _, arg := range args {
+			strs = append(strs, Sprint(arg, opts))
+		}
+		return Value{Type: KindString, Val: strings.Join(strs, " ")}
+	}}
+	env.bindings["str"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		opts := PrintOptions{Printers: env.printers}
+		var strs []string
+		for _, arg := range args {
+			strs = append(strs, Sprint(arg, opts))
+		}
+		return Value{Type: KindString, Val: strings.Join(strs, "")}
+	}}
+	env.bindings["prn"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		fprintln(os.Stdout, args, envPrintOptions(env, true))
+		return Value{Type: KindNil, Val: nil}
+	}}
+	env.bindings["println"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		fprintln(os.Stdout, args, envPrintOptions(env, false))
+		return Value{Type: KindNil, Val: nil}
+	}}
+	env.bindings["pprint"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		// optional second argument is the line width, 80 by default
+		width := 80
+		if len(args) == 2 {
+			validateArgs("pprint", args, []kindSet{anyArg, integerArg})
+			width = int(args[1].Int())
+		} else {
+			validateArgs("pprint", args, []kindSet{anyArg})
+		}
+		fmt.Println(prettyPrint(args[0], width, env.printers))
+		return Value{Type: KindNil, Val: nil}
+	}}
+	env.bindings["print-method"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		// (print-method :type (fn* [v readably] ...)) sets how values of a type print in this env. a tag symbol, e.g.
+		// 'money, sets how tagged literals with that tag print. a nil function restores the default
+		validateArgs("print-method", args, []kindSet{keywordArg | symbolArg, functionArg | functionTCOArg | nilArg})
+		typ := "#" + args[0].Str()
+		if args[0].Type == KindKeyword {
+			typ = args[0].Str()[1:]
+		}
+		fn := getFn(args[1])
+		if fn == nil {
+			delete(env.printers, typ)
+			return Value{Type: KindNil, Val: nil}
+		}
+		env.printers[typ] = func(v Value, readably bool) string {
+			s := fn(v, Value{Type: KindBoolean, Val: readably})
+			if s.Type != KindString {
+				panic("print-method functions must return a string")
+			}
+			return s.Str()
+		}
+		return Value{Type: KindNil, Val: nil}
+	}}
+	env.bindings["fn-name"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		validateArgs("fn-name", args, []kindSet{functionArg | functionTCOArg})
+		if name := fnName(args[0]); name != "" {
+			return Value{Type: KindSymbol, Val: name}
+		}
+		return Value{Type: KindNil, Val: nil}
+	}}
+	env.bindings["arglists"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		// a list of the parameter vectors of a fn*. builtins have no recorded parameters
+		validateArgs("arglists", args, []kindSet{functionArg | functionTCOArg})
+		if args[0].Type == KindFunction {
+			return Value{Type: KindNil, Val: nil}
+		}
+		return Value{Type: KindList, Val: []Value{{Type: KindVector, Val: args[0].Val.(FunctionTCO).Params}}}
+	}}
+	env.bindings["read-string"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		// optional arguments are the file name recorded in the spans of read forms and an options hash-map of read
+		// limits for untrusted input, e.g. (read-string s "data.mal" {:safe true :max-depth 10})
+		var file string
+		var limits Limits
+		switch len(args) {
+		case 1:
+			validateArgs("read-string", args, []kindSet{stringArg})
+		case 2:
+			validateArgs("read-string", args, []kindSet{stringArg, stringArg | hashMapArg})
+		default:
+			validateArgs("read-string", args, []kindSet{stringArg, stringArg, hashMapArg})
+		}
+		for _, arg := range args[1:] {
+			if arg.Type == KindString {
+				file = arg.Str()
+			} else {
+				limits = limitsOption(arg)
+			}
+		}
+		reader := NewEnvReader(env, strings.NewReader(args[0].Str()), file)
+		reader.Limits = limits
+		return readOnly(reader)
+	}}
+	env.bindings["load-file"] = Value{Type: KindFunction, Val: func(args ...Value) Value {
+		validateArgs("load-file", args, []kindSet{stringArg})
+		f, err := os.Open(args[0].Str())
+		if err != nil {
+			panic(fmt.Sprintf("error reading file: %v", err))
+		}
+		defer f.Close()
+		// evaluate form by form so that the file is never held in memory as a whole
+		reader := NewEnvReader(env, f, args[0].Str())
+		for {
+			form, err := reader.ReadForm()
+			if err == io.EOF {
+				return Value{Type: KindNil, Val: nil}
+			}
+			if err != nil {
+				panic(err)
+			}
+			Eval(form, env)
+		}
+	}}
 
+	builtinNamesOnce.Do(func() {
+		builtinNames = map[uintptr]string{}
+		for name, v := range env.bindings {
+			if v.Type == KindFunction {
+				builtinNames[reflect.ValueOf(v.Val).Pointer()] = name
+			}
+		}
+	})
 	return env
 }
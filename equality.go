@@ -0,0 +1,166 @@
+package malarkey
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// hashKey returns a canonical string for a value such that two values are equal iff their keys are equal. It keys
+// sets and is insensitive to the order of set and hash-map entries. Lists and vectors with the same elements are equal.
+func hashKey(v Value) string {
+	var b strings.Builder
+	writeHashKey(&b, v)
+	return b.String()
+}
+
+func writeHashKey(b *strings.Builder, v Value) {
+	switch v.Type {
+	case KindList, KindVector:
+		b.WriteString("(")
+		for _, elem := range v.Elems() {
+			writeHashKey(b, elem)
+			b.WriteString(" ")
+		}
+		b.WriteString(")")
+	case KindHashMap:
+		var entries []string
+		// entries are already keyed by the hashKey of their key
+		for k, entry := range v.Val.(map[string]MapEntry) {
+			entries = append(entries, k+" "+hashKey(entry.Val))
+		}
+		sort.Strings(entries)
+		fmt.Fprintf(b, "{%s}", strings.Join(entries, " "))
+	case KindSet:
+		// set entries are already keyed by hashKey
+		var keys []string
+		for k := range v.Val.(map[string]Value) {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(b, "#{%s}", strings.Join(keys, " "))
+	case KindString:
+		fmt.Fprintf(b, "%q", v.Val)
+	case KindNil:
+		b.WriteString("nil")
+	case KindInteger, KindBigInt, KindRatio:
+		// equal exact numbers of different types have the same key
+		fmt.Fprintf(b, "number:%s", toRat(v).RatString())
+	case KindInst:
+		fmt.Fprintf(b, "inst:%d", v.Val.(time.Time).UnixNano())
+	case KindTaggedLiteral:
+		fmt.Fprintf(b, "#%s ", v.Val.(TaggedLiteral).Tag)
+		writeHashKey(b, v.Val.(TaggedLiteral).Form)
+	case KindFunction, KindFunctionTCO:
+		// functions are hashed by identity, like atoms
+		fmt.Fprintf(b, "fn:%x", fnIdentity(v))
+	case KindAtom:
+		// atoms are mutable references, hashed by identity without following them. this also keeps the key of an atom
+		// that contains itself finite
+		fmt.Fprintf(b, "atom:%p", v.Val.(*Atom))
+	case KindSymbol, KindKeyword, KindUUID:
+		fmt.Fprintf(b, "%s:%q", v.Type, v.Val)
+	case KindRegex:
+		fmt.Fprintf(b, "regex:%q", v.Val.(*regexp.Regexp).String())
+	default:
+		// text payloads are quoted so that a key never runs into the keys of the values after it
+		fmt.Fprintf(b, "%s:%q", v.Type, fmt.Sprint(v.Val))
+	}
+}
+
+// equal returns true if two values are equal. Metadata and source spans are ignored.
+func equal(a, b Value) bool {
+	switch {
+	case isExactNumber(a) && isExactNumber(b):
+		return compareNumbers(a, b) == 0
+	case isSequential(a) && isSequential(b):
+		alist, blist := a.Elems(), b.Elems()
+		if len(alist) != len(blist) {
+			return false
+		}
+		for i := range alist {
+			if !equal(alist[i], blist[i]) {
+				return false
+			}
+		}
+		return true
+	case a.Type != b.Type:
+		return false
+	case a.Type == KindHashMap:
+		akv, bkv := a.Val.(map[string]MapEntry), b.Val.(map[string]MapEntry)
+		if len(akv) != len(bkv) {
+			return false
+		}
+		for k, aentry := range akv {
+			if bentry, ok := bkv[k]; !ok || !equal(aentry.Val, bentry.Val) {
+				return false
+			}
+		}
+		return true
+	case a.Type == KindSet:
+		// elements with equal keys are equal
+		aset, bset := a.Val.(map[string]Value), b.Val.(map[string]Value)
+		if len(aset) != len(bset) {
+			return false
+		}
+		for k := range aset {
+			if _, ok := bset[k]; !ok {
+				return false
+			}
+		}
+		return true
+	case a.Type == KindRegex:
+		return a.Val.(*regexp.Regexp).String() == b.Val.(*regexp.Regexp).String()
+	case a.Type == KindInst:
+		return a.Val.(time.Time).Equal(b.Val.(time.Time))
+	case a.Type == KindTaggedLiteral:
+		atl, btl := a.Val.(TaggedLiteral), b.Val.(TaggedLiteral)
+		return atl.Tag == btl.Tag && equal(atl.Form, btl.Form)
+	case a.Type == KindFunction || a.Type == KindFunctionTCO:
+		return fnIdentity(a) == fnIdentity(b)
+	case a.Type == KindAtom:
+		// by identity, like hashKey. comparing the referenced values could recurse forever through cycles
+		return a.Val.(*Atom) == b.Val.(*Atom)
+	default:
+		return payloadEqual(a.Val, b.Val)
+	}
+}
+
+// payloadEqual compares the payloads of other kinds, such as the kinds of embedding programs, with ==. Payloads that
+// cannot be compared with ==, such as slices and maps, are compared by identity.
+func payloadEqual(a, b interface{}) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta == nil || ta.Comparable() {
+		return a == b
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch va.Kind() {
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	case reflect.Map, reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	}
+	return false
+}
+
+// fnIdentity returns the address of the closure of a function. Each evaluation of fn* makes a new closure, and copies of
+// a function value, such as with new metadata, keep it.
+func fnIdentity(v Value) uintptr {
+	fn := getFn(v)
+	return uintptr(*(*unsafe.Pointer)(unsafe.Pointer(&fn)))
+}
+
+func isExactNumber(v Value) bool {
+	return v.Type == KindInteger || v.Type == KindBigInt || v.Type == KindRatio
+}
+
+func isSequential(v Value) bool {
+	return v.Type == KindList || v.Type == KindVector
+}
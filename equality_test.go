@@ -0,0 +1,40 @@
+package malarkey
+
+import "testing"
+
+func TestHashKeyAgreesWithEqual(t *testing.T) {
+	pairs := []struct {
+		a, b string
+	}{
+		{`[#"a regex:b"]`, `[#"a" #"b"]`},
+		{`[(symbol "a symbol:b")]`, `['a 'b]`},
+		{`[(keyword "a keyword::b")]`, `[:a :b]`},
+		{`[1.5 "x"]`, `[(str 1.5 " float:x")]`},
+		{`{:a 1}`, `{:a 1}`},
+		{`[1 2]`, `(list 1 2)`},
+		{`#{:a "b"}`, `#{"b" :a}`},
+		{`1`, `1.0`},
+		{`"a"`, `'a`},
+		{`:a`, `'a`},
+	}
+	env := BuiltinEnv()
+	for _, pair := range pairs {
+		a, b := Eval(Read(pair.a), env), Eval(Read(pair.b), env)
+		if eq, sameKey := equal(a, b), hashKey(a) == hashKey(b); eq != sameKey {
+			t.Errorf("%s and %s: equal is %v but equal hash keys is %v", pair.a, pair.b, eq, sameKey)
+		}
+	}
+}
+
+func TestEqualUncomparablePayloads(t *testing.T) {
+	k := RegisterKind("test-slice")
+	s := []int{1}
+	a, b := Value{Type: k, Val: s}, Value{Type: k, Val: []int{1}}
+	if !equal(a, a) || equal(a, b) {
+		t.Errorf("slice payloads should be equal by identity")
+	}
+	m := Value{Type: k, Val: map[string]int{}}
+	if !equal(m, m) || equal(m, a) {
+		t.Errorf("map payloads should be equal by identity")
+	}
+}
@@ -1,29 +1,40 @@
 package malarkey
 
 import (
+	"errors"
 	"fmt"
-	"strings"
-
-	"golang.org/x/exp/slices"
 )
 
 // Note: this recommended factoring doesn't click with me. this is like "eval non-function"?
 func evalAST(sexpr Value, env *Env) Value {
 	switch sexpr.Type {
-	case "list", "vector":
+	case KindList, KindVector:
 		var elems []Value
-		for _, elem := range sexpr.Val.([]Value) {
+		for _, elem := range sexpr.Elems() {
 			elems = append(elems, Eval(elem, env))
 		}
+		if sexpr.Type == KindVector {
+			return Value{Type: KindVector, Val: elems, Meta: evalMeta(sexpr.Meta, env)}
+		}
 		return Value{Type: sexpr.Type, Val: elems}
-	case "hash-map":
-		kv := map[string]Value{}
-		for k, v := range sexpr.Val.(map[string]Value) {
-			kv[k] = Eval(v, env)
+	case KindHashMap:
+		var kvs []Value
+		for _, entry := range mapEntries(sexpr) {
+			kvs = append(kvs, Eval(entry.Key, env), Eval(entry.Val, env))
+		}
+		m := newHashMap(kvs)
+		m.Meta = evalMeta(sexpr.Meta, env)
+		return m
+	case KindSet:
+		var elems []Value
+		for _, elem := range sexpr.Val.(map[string]Value) {
+			elems = append(elems, Eval(elem, env))
 		}
-		return Value{Type: "hash-map", Val: kv}
-	case "symbol":
-		s, err := env.Get(sexpr.Val.(string))
+		set := newSet(elems)
+		set.Meta = evalMeta(sexpr.Meta, env)
+		return set
+	case KindSymbol:
+		s, err := env.Get(sexpr.Str())
 		if err != nil {
 			panic(err)
 		}
@@ -33,10 +44,10 @@ func evalAST(sexpr Value, env *Env) Value {
 	}
 }
 
-// panic if args are not of expected length and types. spec types can be "|"-separated unions.
-func validateArgs(fn string, args []Value, spec []string) {
+// panic if args are not of expected length and kinds. a spec ending with moreArgs accepts any further arguments.
+func validateArgs(fn string, args []Value, spec []kindSet) {
 	var isVariadic bool
-	if spec[len(spec)-1] == "*" {
+	if spec[len(spec)-1] == moreArgs {
 		spec = spec[:len(spec)-1]
 		isVariadic = true
 	}
@@ -46,32 +57,60 @@ func validateArgs(fn string, args []Value, spec []string) {
 		panic(fmt.Sprintf("%s requires %d argument(s)", fn, len(spec)))
 	}
 	for i, s := range spec {
-		if s != "any" && !slices.Contains(strings.Split(s, "|"), args[i].Type) {
+		if !s.has(args[i].Type) {
 			panic(fmt.Sprintf("%s %d-idx argument must be of %s type", fn, i, s))
 		}
 	}
 }
 
+// metadata written on a literal is evaluated along with it
+func evalMeta(meta *Value, env *Env) *Value {
+	if meta == nil {
+		return nil
+	}
+	v := Eval(*meta, env)
+	return &v
+}
+
+// metadata on the symbol being defined, e.g. `(def! ^{:doc "..."} f ...)`, is merged into the value's metadata.
+func defMeta(symbol Value, v Value, env *Env) Value {
+	if symbol.Meta == nil || !canHaveMeta(v) {
+		return v
+	}
+	return mergeMeta(v, *evalMeta(symbol.Meta, env))
+}
+
+// nameFn records the name a function is first bound to.
+func nameFn(v Value, name string) Value {
+	if v.Type != KindFunctionTCO || v.Val.(FunctionTCO).Name != "" {
+		return v
+	}
+	f := v.Val.(FunctionTCO)
+	f.Name = name
+	v.Val = f
+	return v
+}
+
 func evalDef(args []Value, env *Env) Value {
-	validateArgs("def!", args, []string{"symbol", "any"})
-	v := Eval(args[1], env)
-	env.Set(args[0].Val.(string), v)
+	validateArgs("def!", args, []kindSet{symbolArg, anyArg})
+	v := nameFn(defMeta(args[0], Eval(args[1], env), env), args[0].Str())
+	env.Set(args[0].Str(), v)
 	return v
 }
 
 // With TCO. Return unevaluated body and new environment.
 func evalLet(args []Value, env *Env) (Value, *Env) {
-	validateArgs("let*", args, []string{"list|vector", "any"})
+	validateArgs("let*", args, []kindSet{listArg | vectorArg, anyArg})
 	letEnv := NewEnv(env, nil, nil)
-	bindings := args[0].Val.([]Value)
+	bindings := args[0].Elems()
 	if len(bindings)%2 != 0 {
 		panic("let* requires an even number of forms in bindings")
 	}
 	for i := 0; i < len(bindings); i += 2 {
-		if bindings[i].Type != "symbol" {
+		if bindings[i].Type != KindSymbol {
 			panic("let* bindings must be symbols")
 		}
-		letEnv.Set(bindings[i].Val.(string), Eval(bindings[i+1], letEnv))
+		letEnv.Set(bindings[i].Str(), Eval(bindings[i+1], letEnv))
 	}
 
 	return args[1], letEnv
@@ -83,11 +122,11 @@ func evalIf(args []Value, env *Env) Value {
 		panic("if requires three (or two) arguments")
 	}
 	cond := Eval(args[0], env)
-	if (cond.Type == "boolean" && !cond.Val.(bool)) || (cond.Type == "nil") {
+	if (cond.Type == KindBoolean && !cond.Bool()) || (cond.Type == KindNil) {
 		if len(args) == 3 {
 			return args[2]
 		}
-		return Value{Type: "nil", Val: nil}
+		return Value{Type: KindNil, Val: nil}
 	}
 	return args[1]
 }
@@ -100,18 +139,18 @@ func evalDo(args []Value, env *Env) Value {
 	return args[len(args)-1]
 }
 
-// With TCO. Return a function-tco value.
-func evalFn(evalArgs []Value, env *Env) Value {
-	validateArgs("fn*", evalArgs, []string{"list|vector", "any"})
-	params := evalArgs[0].Val.([]Value)
+// With TCO. Return a function-tco value. span is the source location of the fn* form.
+func evalFn(evalArgs []Value, span *Span, env *Env) Value {
+	validateArgs("fn*", evalArgs, []kindSet{listArg | vectorArg, anyArg})
+	params := evalArgs[0].Elems()
 	for _, param := range params {
-		if param.Type != "symbol" {
+		if param.Type != KindSymbol {
 			panic("fn* parameters must be symbols")
 		}
 	}
 	body := evalArgs[1]
 
-	return Value{Type: "function-tco", Val: FunctionTCO{
+	return Value{Type: KindFunctionTCO, Val: FunctionTCO{
 		AST:    body,
 		Params: params,
 		Env:    env,
@@ -119,14 +158,15 @@ func evalFn(evalArgs []Value, env *Env) Value {
 			fnEnv := NewEnv(env, params, args)
 			return Eval(body, fnEnv)
 		},
-		IsMacro: false},
+		IsMacro: false,
+		Span:    span},
 	}
 }
 
 func quasiquote(ast Value) Value {
-	if ast.Type == "list" {
-		elems := ast.Val.([]Value)
-		if len(elems) == 2 && elems[0].Type == "symbol" && elems[0].Val.(string) == "unquote" {
+	if ast.Type == KindList {
+		elems := ast.Elems()
+		if len(elems) == 2 && elems[0].Type == KindSymbol && elems[0].Str() == "unquote" {
 			return elems[1]
 		}
 		if len(elems) == 0 {
@@ -134,24 +174,24 @@ func quasiquote(ast Value) Value {
 		}
 
 		elem := elems[0]
-		if elem.Type == "list" {
-			children := elem.Val.([]Value)
-			if len(children) > 0 && children[0].Type == "symbol" && children[0].Val.(string) == "splice-unquote" {
-				return Value{Type: "list", Val: []Value{
-					{Type: "symbol", Val: "concat"},
+		if elem.Type == KindList {
+			children := elem.Elems()
+			if len(children) > 0 && children[0].Type == KindSymbol && children[0].Str() == "splice-unquote" {
+				return Value{Type: KindList, Val: []Value{
+					{Type: KindSymbol, Val: "concat"},
 					children[1],
-					quasiquote(Value{Type: "list", Val: elems[1:]})}}
+					quasiquote(Value{Type: KindList, Val: elems[1:]})}}
 			}
 		}
-		return Value{Type: "list", Val: []Value{
-			{Type: "symbol", Val: "cons"},
+		return Value{Type: KindList, Val: []Value{
+			{Type: KindSymbol, Val: "cons"},
 			quasiquote(elem),
-			quasiquote(Value{Type: "list", Val: elems[1:]}),
+			quasiquote(Value{Type: KindList, Val: elems[1:]}),
 		}}
 	}
-	if ast.Type == "symbol" || ast.Type == "hash-map" {
-		return Value{Type: "list", Val: []Value{
-			{Type: "symbol", Val: "quote"},
+	if ast.Type == KindSymbol || ast.Type == KindHashMap || ast.Type == KindSet {
+		return Value{Type: KindList, Val: []Value{
+			{Type: KindSymbol, Val: "quote"},
 			ast,
 		}}
 	}
@@ -159,35 +199,36 @@ func quasiquote(ast Value) Value {
 }
 
 func evalDefMacro(args []Value, env *Env) Value {
-	validateArgs("defmacro!", args, []string{"symbol", "any"})
-	v := Eval(args[1], env)
-	if v.Type != "function-tco" {
+	validateArgs("defmacro!", args, []kindSet{symbolArg, anyArg})
+	v := nameFn(defMeta(args[0], Eval(args[1], env), env), args[0].Str())
+	if v.Type != KindFunctionTCO {
 		panic("defmacro! requires a macro fn as second argument")
 	}
 	// need to re-wrap the non-ptr Value to update IsMacro = true
 	f := v.Val.(FunctionTCO)
 	f.IsMacro = true
-	env.Set(args[0].Val.(string), Value{Type: "function-tco", Val: f})
-	return v
+	macro := Value{Type: KindFunctionTCO, Val: f, Meta: v.Meta}
+	env.Set(args[0].Str(), macro)
+	return macro
 }
 
 func isMacroCall(ast Value, env *Env) bool {
-	if ast.Type != "list" {
+	if ast.Type != KindList {
 		return false
 	}
-	list := ast.Val.([]Value)
+	list := ast.Elems()
 	if len(list) == 0 {
 		return false
 	}
-	if list[0].Type != "symbol" {
+	if list[0].Type != KindSymbol {
 		return false
 	}
-	symbol := list[0].Val.(string)
+	symbol := list[0].Str()
 	v, err := env.Get(symbol)
 	if err != nil {
 		return false
 	}
-	if v.Type != "function-tco" {
+	if v.Type != KindFunctionTCO {
 		return false
 	}
 	return v.Val.(FunctionTCO).IsMacro
@@ -195,8 +236,8 @@ func isMacroCall(ast Value, env *Env) bool {
 
 func macroExpand(ast Value, env *Env) Value {
 	for isMacroCall(ast, env) {
-		elems := ast.Val.([]Value)
-		symbol := elems[0].Val.(string)
+		elems := ast.Elems()
+		symbol := elems[0].Str()
 		macro, err := env.Get(symbol)
 		if err != nil {
 			continue
@@ -206,14 +247,88 @@ func macroExpand(ast Value, env *Env) Value {
 	return ast
 }
 
+// EvalError is an error raised during evaluation, annotated with the source span of the innermost form being evaluated
+// that has one.
+type EvalError struct {
+	Span *Span
+	Err  error
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Span, e.Err)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// annotatePanic is deferred by Eval. It re-panics string and error panics as an *EvalError with the span of the form
+// being evaluated. Thrown mal values, read errors and already annotated errors pass through unchanged.
+func annotatePanic(expr *Value) {
+	if expr.Span == nil {
+		return
+	}
+	switch r := recover().(type) {
+	case nil:
+	case string:
+		panic(&EvalError{Span: expr.Span, Err: errors.New(r)})
+	case *EvalError, *SyntaxError, *LimitError:
+		panic(r)
+	case error:
+		panic(&EvalError{Span: expr.Span, Err: r})
+	default:
+		panic(r)
+	}
+}
+
+// errorValue returns the value that catch* binds for an error. Syntax and limit errors are caught as hash-maps so that
+// they can be inspected, e.g. {:type :syntax :msg "..." :incomplete false :line 1 :col 3} or
+// {:type :limit :limit "depth" :max 2 :line 1 :col 3}, with a :file if the input had one. Other errors are caught as
+// their message.
+func errorValue(err error) Value {
+	var syntaxErr *SyntaxError
+	var limitErr *LimitError
+	var evalErr *EvalError
+	var fields map[string]Value
+	var file string
+	var pos Pos
+	switch {
+	case errors.As(err, &syntaxErr):
+		fields = map[string]Value{
+			":type":       {Type: KindKeyword, Val: ":syntax"},
+			":msg":        {Type: KindString, Val: syntaxErr.Msg},
+			":incomplete": {Type: KindBoolean, Val: syntaxErr.Incomplete},
+		}
+		file, pos = syntaxErr.File, syntaxErr.Pos
+	case errors.As(err, &limitErr):
+		fields = map[string]Value{
+			":type":  {Type: KindKeyword, Val: ":limit"},
+			":limit": {Type: KindString, Val: limitErr.Limit},
+			":max":   {Type: KindInteger, Val: int64(limitErr.Max)},
+		}
+		file, pos = limitErr.File, limitErr.Pos
+	case errors.As(err, &evalErr):
+		return Value{Type: KindString, Val: evalErr.Err.Error()}
+	default:
+		return Value{Type: KindString, Val: err.Error()}
+	}
+	fields[":line"] = Value{Type: KindInteger, Val: int64(pos.Line)}
+	fields[":col"] = Value{Type: KindInteger, Val: int64(pos.Col)}
+	if file != "" {
+		fields[":file"] = Value{Type: KindString, Val: file}
+	}
+	return keywordMap(fields)
+}
+
 func try(expr Value, env *Env) (value, exceptionValue *Value) {
 	defer func() {
 		if r := recover(); r != nil {
 			switch v := r.(type) {
 			case string:
-				exceptionValue = &Value{Type: "string", Val: v}
+				exceptionValue = &Value{Type: KindString, Val: v}
 			case error:
-				exceptionValue = &Value{Type: "string", Val: v.Error()}
+				val := errorValue(v)
+				exceptionValue = &val
 			case Value:
 				exceptionValue = &v
 			}
@@ -224,10 +339,10 @@ func try(expr Value, env *Env) (value, exceptionValue *Value) {
 }
 
 func evalTryCatch(args []Value, env *Env) Value {
-	validateArgs("try*", args, []string{"any", "list"})
-	catchForm := args[1].Val.([]Value)
-	validateArgs("catch*", catchForm, []string{"symbol", "symbol", "any"})
-	if catchForm[0].Val.(string) != "catch*" {
+	validateArgs("try*", args, []kindSet{anyArg, listArg})
+	catchForm := args[1].Elems()
+	validateArgs("catch*", catchForm, []kindSet{symbolArg, symbolArg, anyArg})
+	if catchForm[0].Str() != "catch*" {
 		panic("try* requires a symbol 'catch* as first element of second argument")
 	}
 
@@ -241,26 +356,28 @@ func evalTryCatch(args []Value, env *Env) Value {
 
 // Eval evaluates an expression in the given environment.
 func Eval(expr Value, env *Env) Value {
+	defer annotatePanic(&expr)
+
 	// Tail call optimization prevents nested function calls.
 	for {
-		if expr.Type != "list" {
+		if expr.Type != KindList {
 			return evalAST(expr, env)
 		}
-		if len(expr.Val.([]Value)) == 0 {
+		if len(expr.Elems()) == 0 {
 			return expr
 		}
 
 		// macro expansion
 		expr = macroExpand(expr, env)
-		if expr.Type != "list" {
+		if expr.Type != KindList {
 			return evalAST(expr, env)
 		}
-		list := expr.Val.([]Value)
+		list := expr.Elems()
 
 		// special forms
-		if list[0].Type == "symbol" {
+		if list[0].Type == KindSymbol {
 			args := list[1:]
-			switch list[0].Val.(string) {
+			switch list[0].Str() {
 			case "def!":
 				return evalDef(args, env)
 			case "defmacro!":
@@ -276,7 +393,9 @@ func Eval(expr Value, env *Env) Value {
 				expr = evalDo(args, env)
 				continue
 			case "fn*":
-				return evalFn(args, env)
+				fn := evalFn(args, expr.Span, env)
+				fn.Meta = evalMeta(expr.Meta, env)
+				return fn
 			case "quote":
 				return args[0]
 			case "quasiquote":
@@ -291,12 +410,12 @@ func Eval(expr Value, env *Env) Value {
 
 		// function call
 		evaluatedList := evalAST(expr, env)
-		elems := evaluatedList.Val.([]Value)
+		elems := evaluatedList.Elems()
 		switch elems[0].Type {
-		case "function":
+		case KindFunction:
 			fn := elems[0].Val.(func(args ...Value) Value)
 			return fn(elems[1:]...)
-		case "function-tco":
+		case KindFunctionTCO:
 			args := elems[1:]
 			fn := elems[0].Val.(FunctionTCO)
 
@@ -1,5 +1,3 @@
 module github.com/elh/mal-arkey
 
 go 1.21.1
-
-require golang.org/x/exp v0.0.0-20230905200255-921286631fa9
@@ -0,0 +1,95 @@
+package malarkey
+
+import "sort"
+
+// MapEntry is a key and value of a hash-map. Entries are keyed by the hashKey of their key so that keys of any type
+// can be looked up by equality.
+type MapEntry struct {
+	Key Value
+	Val Value
+}
+
+// newHashMap creates a hash-map of alternating keys and values. A later value of an equal key replaces earlier ones.
+func newHashMap(kvs []Value) Value {
+	if len(kvs)%2 != 0 {
+		panic("hash-map requires an even number of keys and values")
+	}
+	kv := make(map[string]MapEntry, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		kv[hashKey(kvs[i])] = MapEntry{Key: kvs[i], Val: kvs[i+1]}
+	}
+	return Value{Type: KindHashMap, Val: kv}
+}
+
+// keywordMap creates a hash-map with keyword keys, e.g. keywordMap(map[string]Value{":tag": v}) is {:tag v}.
+func keywordMap(kws map[string]Value) Value {
+	kv := make(map[string]MapEntry, len(kws))
+	for kw, val := range kws {
+		key := Value{Type: KindKeyword, Val: kw}
+		kv[hashKey(key)] = MapEntry{Key: key, Val: val}
+	}
+	return Value{Type: KindHashMap, Val: kv}
+}
+
+// mapGet returns the value of a key in a hash-map.
+func mapGet(m Value, key Value) (Value, bool) {
+	entry, ok := m.Val.(map[string]MapEntry)[hashKey(key)]
+	return entry.Val, ok
+}
+
+// mapEntries returns the entries of a hash-map in an unspecified order.
+func mapEntries(m Value) []MapEntry {
+	var entries []MapEntry
+	for _, entry := range m.Val.(map[string]MapEntry) {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// sortedMapEntries returns the entries of a hash-map ordered by the hashKey of their keys. It is the order hash-maps
+// print in, which is stable without printing the keys first. If n > 0 only the first n entries are returned.
+func sortedMapEntries(m Value, n int) []MapEntry {
+	kv := m.Val.(map[string]MapEntry)
+	keys := firstKeys(kv, n)
+	entries := make([]MapEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = kv[k]
+	}
+	return entries
+}
+
+// firstKeys returns the first n keys of a map in sorted order, or all of them if n <= 0. It does not sort the keys
+// past n, so that printing the start of a large collection is cheap.
+func firstKeys[V any](m map[string]V, n int) []string {
+	if n <= 0 || n >= len(m) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	keys := make([]string, 0, n+1)
+	for k := range m {
+		if len(keys) == n && k >= keys[n-1] {
+			continue
+		}
+		i := sort.SearchStrings(keys, k)
+		keys = append(keys, "")
+		copy(keys[i+1:], keys[i:])
+		keys[i] = k
+		if len(keys) > n {
+			keys = keys[:n]
+		}
+	}
+	return keys
+}
+
+// copyHashMap returns a shallow copy of a hash-map's entries so that it can be modified without mutating the original.
+func copyHashMap(m Value) map[string]MapEntry {
+	kv := make(map[string]MapEntry, len(m.Val.(map[string]MapEntry)))
+	for k, entry := range m.Val.(map[string]MapEntry) {
+		kv[k] = entry
+	}
+	return kv
+}
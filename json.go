@@ -0,0 +1,279 @@
+package malarkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON values read as:
+// * null    - nil
+// * boolean - boolean
+// * number  - integer, bigint if it does not fit in an int64, or float if it has a fraction or exponent
+// * string  - string
+// * array   - vector
+// * object  - hash-map with string keys, or keyword keys if keywordized
+//
+// Writing is the reverse, and additionally writes lists and sets as arrays, keywords, symbols and chars as strings,
+// ratios as floats, insts as RFC 3339 strings and uuids as strings. Hash-map keys must be strings, keywords, symbols or
+// numbers, and distinct keys must not write as the same JSON key, like :a and "a". Other values, such as functions and
+// atoms, are errors.
+
+// ParseJSON parses a single JSON value. keywordize reads object keys as keywords.
+func ParseJSON(src string, keywordize bool) (Value, error) {
+	d := NewJSONDecoder(strings.NewReader(src))
+	d.Keywordize = keywordize
+	v, err := d.Decode()
+	if err == io.EOF {
+		return Value{}, errors.New("unexpected end of JSON input")
+	} else if err != nil {
+		return Value{}, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return Value{}, errors.New("invalid JSON: unexpected data after the top-level value")
+	}
+	return v, nil
+}
+
+// JSONDecoder decodes a stream of JSON values, such as a newline-delimited JSON file, one value at a time.
+type JSONDecoder struct {
+	Keywordize bool // read object keys as keywords
+
+	dec *json.Decoder
+}
+
+// NewJSONDecoder creates a JSONDecoder that reads from r.
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &JSONDecoder{dec: dec}
+}
+
+// Decode returns the next JSON value. It returns io.EOF when there are no more values.
+func (d *JSONDecoder) Decode() (Value, error) {
+	token, err := d.dec.Token()
+	if err != nil {
+		return Value{}, err
+	}
+	return d.decode(token)
+}
+
+func (d *JSONDecoder) decode(token json.Token) (Value, error) {
+	switch t := token.(type) {
+	case nil:
+		return Value{Type: KindNil, Val: nil}, nil
+	case bool:
+		return Value{Type: KindBoolean, Val: t}, nil
+	case string:
+		return Value{Type: KindString, Val: t}, nil
+	case json.Number:
+		return jsonNumber(t)
+	case json.Delim:
+		var elems []Value
+		for d.dec.More() {
+			if t == '{' {
+				key, err := d.dec.Token()
+				if err != nil {
+					return Value{}, err
+				}
+				if d.Keywordize {
+					elems = append(elems, Value{Type: KindKeyword, Val: ":" + key.(string)})
+				} else {
+					elems = append(elems, Value{Type: KindString, Val: key.(string)})
+				}
+			}
+			token, err := d.dec.Token()
+			if err != nil {
+				return Value{}, err
+			}
+			elem, err := d.decode(token)
+			if err != nil {
+				return Value{}, err
+			}
+			elems = append(elems, elem)
+		}
+		// the closing delimiter
+		if _, err := d.dec.Token(); err != nil {
+			return Value{}, err
+		}
+		if t == '{' {
+			return newHashMap(elems), nil
+		}
+		return Value{Type: KindVector, Val: elems}, nil
+	}
+	return Value{}, fmt.Errorf("invalid JSON token %v", token)
+}
+
+func jsonNumber(n json.Number) (Value, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return Value{Type: KindInteger, Val: i}, nil
+		}
+		if i, ok := new(big.Int).SetString(s, 10); ok {
+			return Value{Type: KindBigInt, Val: i}, nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Value{}, fmt.Errorf("invalid JSON number %s", s)
+	}
+	return Value{Type: KindFloat, Val: f}, nil
+}
+
+// WriteJSON returns the JSON text of a value. It is compact if indent is empty, and otherwise pretty printed with
+// indent per level of nesting. Hash-map keys are written in sorted order.
+func WriteJSON(v Value, indent string) (string, error) {
+	var b bytes.Buffer
+	if err := writeJSON(&b, v); err != nil {
+		return "", err
+	}
+	if indent == "" {
+		return b.String(), nil
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, b.Bytes(), "", indent); err != nil {
+		return "", err
+	}
+	return pretty.String(), nil
+}
+
+func writeJSON(b *bytes.Buffer, v Value) error {
+	switch v.Type {
+	case KindNil:
+		b.WriteString("null")
+	case KindBoolean:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case KindInteger, KindBigInt:
+		b.WriteString(toBigInt(v).String())
+	case KindRatio, KindFloat:
+		var f float64
+		if v.Type == KindRatio {
+			f, _ = v.Val.(*big.Rat).Float64()
+		} else {
+			f = v.Float()
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("cannot write %v as JSON", f)
+		}
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case KindString, KindSymbol, KindUUID:
+		writeJSONString(b, v.Str())
+	case KindKeyword:
+		writeJSONString(b, v.Str()[1:])
+	case KindChar:
+		writeJSONString(b, string(v.Val.(rune)))
+	case KindInst:
+		writeJSONString(b, printInst(v.Val.(time.Time)))
+	case KindList, KindVector, KindSet:
+		var elems []Value
+		if v.Type == KindSet {
+			// sets are written in the order they print for a stable output
+			elems = sortedSetElems(v, 0)
+		} else {
+			elems = v.Elems()
+		}
+		b.WriteByte('[')
+		for i, elem := range elems {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, elem); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case KindHashMap:
+		entries := sortedMapEntries(v, 0)
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			key, err := jsonKey(entry.Key)
+			if err != nil {
+				return err
+			}
+			keys[i] = key
+		}
+		sort.Stable(byKey{keys, entries})
+		for i := 1; i < len(keys); i++ {
+			if keys[i] == keys[i-1] {
+				return fmt.Errorf("cannot write hash-map keys %s and %s as JSON: both are the key %q",
+					Print(entries[i-1].Key, true), Print(entries[i].Key, true), keys[i])
+			}
+		}
+		b.WriteByte('{')
+		for i, entry := range entries {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONString(b, keys[i])
+			b.WriteByte(':')
+			if err := writeJSON(b, entry.Val); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot write %s as JSON", v.Type)
+	}
+	return nil
+}
+
+// jsonKey returns the object key of a hash-map key.
+func jsonKey(k Value) (string, error) {
+	switch k.Type {
+	case KindString, KindSymbol:
+		return k.Str(), nil
+	case KindKeyword:
+		return k.Str()[1:], nil
+	case KindInteger, KindBigInt:
+		return toBigInt(k).String(), nil
+	}
+	return "", fmt.Errorf("cannot write a %s hash-map key as JSON", k.Type)
+}
+
+// writeJSONString writes a JSON string literal. Unlike encoding/json it does not escape HTML characters.
+func writeJSONString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// byKey sorts map entries by their JSON keys.
+type byKey struct {
+	keys    []string
+	entries []MapEntry
+}
+
+func (s byKey) Len() int           { return len(s.keys) }
+func (s byKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s byKey) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+}
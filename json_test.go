@@ -0,0 +1,40 @@
+package malarkey_test
+
+import (
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		src        string
+		keywordize bool
+		want       string // the readable print of the parsed value, or "" for an error
+	}{
+		{`null`, false, `nil`},
+		{`[1, 2.5, "s", true, null]`, false, `[1 2.5 "s" true nil]`},
+		{`{"a": {"b": []}}`, false, `{"a" {"b" []}}`},
+		{`{"a": {"b": []}}`, true, `{:a {:b []}}`},
+		{`123456789012345678901234567890`, false, `123456789012345678901234567890N`},
+		{`1e2`, false, `100.0`},
+		{`"é\n"`, false, `"é\n"`},
+		{``, false, ``},
+		{`[1,]`, false, ``},
+		{`1 2`, false, ``},
+	}
+	for _, test := range tests {
+		v, err := mal.ParseJSON(test.src, test.keywordize)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("ParseJSON(%q) = %s, want an error", test.src, mal.Print(v, true))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseJSON(%q): %v", test.src, err)
+		} else if got := mal.Print(v, true); got != test.want {
+			t.Errorf("ParseJSON(%q) = %s, want %s", test.src, got, test.want)
+		}
+	}
+}
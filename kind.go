@@ -0,0 +1,194 @@
+package malarkey
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Kind is the type of a Value.
+type Kind uint8
+
+// Value kinds. The zero Value is nil.
+const (
+	KindNil Kind = iota
+	KindList
+	KindVector
+	KindHashMap
+	KindSet
+	KindSymbol
+	KindString
+	KindChar
+	KindInteger
+	KindFloat
+	KindBigInt
+	KindRatio
+	KindBoolean
+	KindKeyword
+	KindRegex
+	KindInst
+	KindUUID
+	KindTaggedLiteral
+	KindAtom
+	KindFunction
+	KindFunctionTCO
+	numKinds // kinds registered by RegisterKind follow
+)
+
+// kindNames are the type names of kinds, as used in validateArgs specs, error messages and RegisterPrinter.
+var kindNames = [numKinds]string{
+	"nil", "list", "vector", "hash-map", "set", "symbol", "string", "char", "integer", "float", "bigint", "ratio",
+	"boolean", "keyword", "regex", "inst", "uuid", "tagged-literal", "atom", "function", "function-tco",
+}
+
+// kinds of the types of embedding programs, registered by RegisterKind
+var (
+	customKindsMu sync.RWMutex
+	customKinds   []string
+)
+
+func (k Kind) String() string {
+	if k < numKinds {
+		return kindNames[k]
+	}
+	customKindsMu.RLock()
+	defer customKindsMu.RUnlock()
+	if i := int(k - numKinds); i < len(customKinds) {
+		return customKinds[i]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+// KindOf returns the kind of a type name, e.g. KindOf("hash-map") is KindHashMap. Type names of embedding programs
+// must be registered with RegisterKind first.
+func KindOf(name string) (Kind, error) {
+	for k, kindName := range kindNames {
+		if kindName == name {
+			return Kind(k), nil
+		}
+	}
+	customKindsMu.RLock()
+	defer customKindsMu.RUnlock()
+	for i, kindName := range customKinds {
+		if kindName == name {
+			return numKinds + Kind(i), nil
+		}
+	}
+	return KindNil, fmt.Errorf("unknown value type %s", name)
+}
+
+// RegisterKind returns a new kind for a type of an embedding program, so that it can make values of its own types,
+// e.g. Value{Type: RegisterKind("handle"), Val: h}, and print them with RegisterPrinter. Registering a name again
+// returns the same kind. It panics if name is a mal type.
+func RegisterKind(name string) Kind {
+	for _, kindName := range kindNames {
+		if kindName == name {
+			panic(fmt.Sprintf("%s is a built-in value type", name))
+		}
+	}
+	customKindsMu.Lock()
+	defer customKindsMu.Unlock()
+	for i, kindName := range customKinds {
+		if kindName == name {
+			return numKinds + Kind(i)
+		}
+	}
+	if int(numKinds)+len(customKinds) > 255 {
+		panic("too many value kinds")
+	}
+	customKinds = append(customKinds, name)
+	return numKinds + Kind(len(customKinds)-1)
+}
+
+// NewValue creates a value of a type name. It eases porting code that used the type names of the string-typed Value.
+// It panics if the type name is unknown.
+func NewValue(typeName string, val interface{}) Value {
+	k, err := KindOf(typeName)
+	if err != nil {
+		panic(err)
+	}
+	return Value{Type: k, Val: val}
+}
+
+// TypeName returns the type name of a value's kind.
+func (v Value) TypeName() string {
+	return v.Type.String()
+}
+
+// Typed accessors. They panic if the value is not of the expected kind.
+
+// Int returns the value of an integer.
+func (v Value) Int() int64 {
+	return v.Val.(int64)
+}
+
+// Float returns the value of a float.
+func (v Value) Float() float64 {
+	return v.Val.(float64)
+}
+
+// Bool returns the value of a boolean.
+func (v Value) Bool() bool {
+	return v.Val.(bool)
+}
+
+// Str returns the text of a string, symbol or keyword. Keywords include their leading colon.
+func (v Value) Str() string {
+	return v.Val.(string)
+}
+
+// Elems returns the elements of a list or vector.
+func (v Value) Elems() []Value {
+	return v.Val.([]Value)
+}
+
+// kindSet is a set of the built-in kinds as a bitmask, used by validateArgs specs. Specs are unions of the kind
+// constants below, e.g. listArg | vectorArg, so that they are computed at compile time.
+type kindSet uint64
+
+// kind sets of validateArgs specs
+const (
+	nilArg           kindSet = 1 << KindNil
+	listArg          kindSet = 1 << KindList
+	vectorArg        kindSet = 1 << KindVector
+	hashMapArg       kindSet = 1 << KindHashMap
+	setArg           kindSet = 1 << KindSet
+	symbolArg        kindSet = 1 << KindSymbol
+	stringArg        kindSet = 1 << KindString
+	charArg          kindSet = 1 << KindChar
+	integerArg       kindSet = 1 << KindInteger
+	floatArg         kindSet = 1 << KindFloat
+	bigIntArg        kindSet = 1 << KindBigInt
+	ratioArg         kindSet = 1 << KindRatio
+	booleanArg       kindSet = 1 << KindBoolean
+	keywordArg       kindSet = 1 << KindKeyword
+	regexArg         kindSet = 1 << KindRegex
+	instArg          kindSet = 1 << KindInst
+	uuidArg          kindSet = 1 << KindUUID
+	taggedLiteralArg kindSet = 1 << KindTaggedLiteral
+	atomArg          kindSet = 1 << KindAtom
+	functionArg      kindSet = 1 << KindFunction
+	functionTCOArg   kindSet = 1 << KindFunctionTCO
+	anyArg           kindSet = 1<<64 - 1 // any kind, including the kinds of embedding programs
+	// moreArgs ends a variadic spec: any number of further arguments of any kind
+	moreArgs kindSet = 0
+)
+
+// has returns true if a kind is in the set.
+func (s kindSet) has(k Kind) bool {
+	return s == anyArg || (k < 64 && s&(1<<k) != 0)
+}
+
+// String returns the type names of the set, e.g. "list|vector", or "any".
+func (s kindSet) String() string {
+	if s == anyArg {
+		return "any"
+	}
+	var names []string
+	for k := Kind(0); k < numKinds; k++ {
+		if s.has(k) {
+			names = append(names, k.String())
+		}
+	}
+	return strings.Join(names, "|")
+}
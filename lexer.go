@@ -0,0 +1,425 @@
+package malarkey
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind is the lexical category of a Token.
+type TokenKind int
+
+// Token kinds.
+const (
+	TokenEOF        TokenKind = iota
+	TokenOpen                 // ( [ { #{ #(
+	TokenClose                // ) ] }
+	TokenMacro                // ' ` ~ ~@ @ ^ #_ #? #?@ and tags, e.g. #inst
+	TokenString               // "..." or raw """...""" including the quotes and escapes as written
+	TokenAtom                 // numbers, keywords, symbols, true, false, nil
+	TokenChar                 // \a \newline \u00e9
+	TokenRegex                // #"..." including the quotes and escapes as written
+	TokenWhitespace           // whitespace and commas. only emitted with KeepTrivia
+	TokenComment              // ; to the end of the line, excluding the newline. only emitted with KeepTrivia
+)
+
+func (k TokenKind) String() string {
+	return [...]string{"EOF", "open", "close", "macro", "string", "atom", "char", "regex", "whitespace", "comment"}[k]
+}
+
+// Token is a lexical token and its position in the source text.
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Start Pos
+	End   Pos
+}
+
+// SyntaxError is an error in the source text being read.
+type SyntaxError struct {
+	File       string
+	Pos        Pos
+	Msg        string
+	Incomplete bool // the input ended before a form was complete. more input may make it valid
+}
+
+func (e *SyntaxError) Error() string {
+	if e.File == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+// Lexer splits source text into tokens. Whitespace, commas and comments are skipped unless KeepTrivia is set.
+type Lexer struct {
+	File       string // file name recorded in syntax errors. optional
+	KeepTrivia bool   // emit whitespace and comment tokens
+	MaxBytes   int    // fail with a *LimitError when the source text is longer. 0 is unlimited
+
+	src     runeByteScanner
+	pos     Pos
+	prevPos Pos  // position before the last read rune, restored by unread
+	prevRaw bool // the last read rune was an invalid UTF-8 byte
+}
+
+type runeByteScanner interface {
+	io.RuneScanner
+	io.ByteScanner
+}
+
+// NewLexer creates a Lexer that reads from r.
+func NewLexer(r io.Reader, file string) *Lexer {
+	src, ok := r.(runeByteScanner)
+	if !ok {
+		src = bufio.NewReader(r)
+	}
+	return &Lexer{File: file, src: src, pos: Pos{Line: 1, Col: 1}}
+}
+
+// Tokenize splits a input text into tokens.
+func Tokenize(input string) ([]Token, error) {
+	lexer := NewLexer(strings.NewReader(input), "")
+	var out []Token
+	for {
+		token, err := lexer.Next()
+		if err != nil {
+			return nil, err
+		}
+		if token.Kind == TokenEOF {
+			return out, nil
+		}
+		out = append(out, token)
+	}
+}
+
+// Next returns the next token. At the end of the input it returns a TokenEOF token.
+func (l *Lexer) Next() (token Token, err error) {
+	if l.KeepTrivia {
+		if token, ok, err := l.readTrivia(); err != nil || ok {
+			return token, err
+		}
+	} else if err := l.skip(); err != nil {
+		return Token{}, err
+	}
+	start := l.pos
+	r, err := l.read()
+	if err == io.EOF {
+		return Token{Kind: TokenEOF, Start: start, End: start}, nil
+	} else if err != nil {
+		return Token{}, err
+	}
+
+	var kind TokenKind
+	var text strings.Builder
+	writeRune(&text, r)
+	switch r {
+	case '(', '[', '{':
+		kind = TokenOpen
+	case ')', ']', '}':
+		kind = TokenClose
+	case '\'', '`', '^', '@':
+		kind = TokenMacro
+	case '~':
+		kind = TokenMacro
+		if next, err := l.read(); err == nil && next == '@' {
+			writeRune(&text, next)
+		} else if err == nil {
+			l.unread()
+		}
+	case '#':
+		if kind, err = l.readDispatch(&text, start); err != nil {
+			return Token{}, err
+		}
+	case '\\':
+		// the first character is taken literally even if it is a delimiter, e.g. `\(`
+		kind = TokenChar
+		next, err := l.read()
+		if err == io.EOF {
+			return Token{}, &SyntaxError{File: l.File, Pos: start, Msg: fmt.Sprintf("unterminated character literal at %s", start), Incomplete: true}
+		} else if err != nil {
+			return Token{}, err
+		}
+		writeRune(&text, next)
+		if err := l.readAtom(&text); err != nil {
+			return Token{}, err
+		}
+	case '"':
+		kind = TokenString
+		if err := l.readString(&text, start); err != nil {
+			return Token{}, err
+		}
+	default:
+		kind = TokenAtom
+		if err := l.readAtom(&text); err != nil {
+			return Token{}, err
+		}
+	}
+	return Token{Kind: kind, Text: text.String(), Start: start, End: l.pos}, nil
+}
+
+// skip advances past whitespace, commas and comments.
+func (l *Lexer) skip() error {
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case r == ';':
+			for r != '\n' {
+				if r, err = l.read(); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		case r == ',' || isSpace(r):
+		default:
+			l.unread()
+			return nil
+		}
+	}
+}
+
+// readTrivia reads a whitespace or comment token if one is next.
+func (l *Lexer) readTrivia() (token Token, ok bool, err error) {
+	start := l.pos
+	r, err := l.read()
+	if err == io.EOF {
+		return Token{}, false, nil
+	} else if err != nil {
+		return Token{}, false, err
+	}
+
+	var kind TokenKind
+	var text strings.Builder
+	writeRune(&text, r)
+	switch {
+	case r == ',' || isSpace(r):
+		kind = TokenWhitespace
+		err = l.readWhile(&text, func(r rune) bool { return r == ',' || isSpace(r) })
+	case r == ';':
+		kind = TokenComment
+		err = l.readWhile(&text, func(r rune) bool { return r != '\n' })
+	default:
+		l.unread()
+		return Token{}, false, nil
+	}
+	if err != nil {
+		return Token{}, false, err
+	}
+	return Token{Kind: kind, Text: text.String(), Start: start, End: l.pos}, true, nil
+}
+
+// readWhile reads runes while they satisfy cond.
+func (l *Lexer) readWhile(text *strings.Builder, cond func(rune) bool) error {
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !cond(r) {
+			l.unread()
+			return nil
+		}
+		writeRune(text, r)
+	}
+}
+
+// readDispatch reads the rest of a token starting with `#`.
+func (l *Lexer) readDispatch(text *strings.Builder, start Pos) (TokenKind, error) {
+	next, err := l.read()
+	if err == io.EOF {
+		return TokenAtom, nil
+	} else if err != nil {
+		return 0, err
+	}
+	switch next {
+	case '{', '(':
+		writeRune(text, next)
+		return TokenOpen, nil
+	case '_':
+		writeRune(text, next)
+		return TokenMacro, nil
+	case '"':
+		writeRune(text, next)
+		return TokenRegex, l.readQuoted(text, start, "regex")
+	case '?':
+		writeRune(text, next)
+		if next, err := l.read(); err == nil && next == '@' {
+			writeRune(text, next)
+		} else if err == nil {
+			l.unread()
+		}
+		return TokenMacro, nil
+	}
+	l.unread()
+	if unicode.IsLetter(next) {
+		// the tag of a tagged literal, e.g. `#inst`
+		return TokenMacro, l.readAtom(text)
+	}
+	return TokenAtom, l.readAtom(text)
+}
+
+// readString reads the rest of a string literal after its opening quote. Escapes are kept as written.
+func (l *Lexer) readString(text *strings.Builder, start Pos) error {
+	// `""` is an empty string unless it opens a raw string with `"""`
+	if r, err := l.read(); err == nil && r == '"' {
+		writeRune(text, r)
+		if r, err := l.read(); err == nil && r == '"' {
+			writeRune(text, r)
+			return l.readRawString(text, start)
+		} else if err == nil {
+			l.unread()
+		}
+		return nil
+	} else if err == nil {
+		l.unread()
+	}
+	return l.readQuoted(text, start, "string")
+}
+
+// readQuoted reads up to and including an unescaped closing quote. Escapes are kept as written.
+func (l *Lexer) readQuoted(text *strings.Builder, start Pos, what string) error {
+	var escaped bool
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return &SyntaxError{File: l.File, Pos: start, Msg: fmt.Sprintf("unterminated %s starting at %s", what, start), Incomplete: true}
+		} else if err != nil {
+			return err
+		}
+		writeRune(text, r)
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return nil
+		}
+	}
+}
+
+// readRawString reads the rest of a raw string literal after its opening `"""` up to and including the closing `"""`.
+// A run of more than three quotes closes the string with its last three, so that raw content can end with quotes, e.g.
+// `"""say "hi""""` is `say "hi"`. Raw content cannot contain `"""` itself.
+func (l *Lexer) readRawString(text *strings.Builder, start Pos) error {
+	var quotes int
+	for quotes < 3 {
+		r, err := l.read()
+		if err == io.EOF {
+			return &SyntaxError{File: l.File, Pos: start, Msg: fmt.Sprintf("unterminated string starting at %s", start), Incomplete: true}
+		} else if err != nil {
+			return err
+		}
+		writeRune(text, r)
+		if r == '"' {
+			quotes++
+		} else {
+			quotes = 0
+		}
+	}
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if r != '"' {
+			l.unread()
+			return nil
+		}
+		writeRune(text, r)
+	}
+}
+
+// readAtom reads the rest of an atom up to the next delimiter.
+func (l *Lexer) readAtom(text *strings.Builder) error {
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if isDelimiter(r) {
+			l.unread()
+			return nil
+		}
+		writeRune(text, r)
+	}
+}
+
+func (l *Lexer) read() (rune, error) {
+	r, size, err := l.src.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	l.prevRaw = r == utf8.RuneError && size == 1
+	if l.prevRaw {
+		_ = l.src.UnreadRune()
+		b, _ := l.src.ReadByte()
+		r = rawByteRune + rune(b)
+	}
+	if l.MaxBytes > 0 && l.pos.Offset+size > l.MaxBytes {
+		return 0, &LimitError{File: l.File, Pos: l.pos, Limit: "bytes", Max: l.MaxBytes}
+	}
+	l.prevPos = l.pos
+	l.pos.Offset += size
+	if r == '\n' {
+		l.pos.Line++
+		l.pos.Col = 1
+	} else {
+		l.pos.Col++
+	}
+	return r, nil
+}
+
+// unread steps back the last read rune. It may only be called once after read.
+func (l *Lexer) unread() {
+	if l.prevRaw {
+		_ = l.src.UnreadByte()
+	} else {
+		_ = l.src.UnreadRune()
+	}
+	l.pos = l.prevPos
+}
+
+// invalid UTF-8 bytes are read as the runes rawByteRune+0x80 to rawByteRune+0xff, which are surrogates that valid UTF-8
+// cannot encode, so that token text keeps the bytes as written
+const rawByteRune = 0xdc00
+
+// writeRune writes a rune read by the lexer to token text.
+func writeRune(text *strings.Builder, r rune) {
+	if r >= rawByteRune+0x80 && r <= rawByteRune+0xff {
+		text.WriteByte(byte(r - rawByteRune))
+		return
+	}
+	text.WriteRune(r)
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// isDelimiter reports whether r ends an atom.
+func isDelimiter(r rune) bool {
+	switch r {
+	case '(', ')', '[', ']', '{', '}', '\'', '"', '`', ',', ';':
+		return true
+	}
+	return isSpace(r)
+}
@@ -0,0 +1,49 @@
+package malarkey
+
+// Metadata is a hash-map attached to a Value that does not affect its equality. Collections, symbols and functions
+// can carry metadata.
+
+// canHaveMeta returns true if the value type supports metadata.
+func canHaveMeta(v Value) bool {
+	switch v.Type {
+	case KindList, KindVector, KindHashMap, KindSet, KindSymbol, KindFunction, KindFunctionTCO:
+		return true
+	}
+	return false
+}
+
+// withMeta returns v with its metadata replaced. meta must be a hash-map or nil.
+func withMeta(v Value, meta Value) Value {
+	if !canHaveMeta(v) {
+		panic("cannot attach metadata to " + v.Type.String())
+	}
+	if meta.Type == KindNil {
+		v.Meta = nil
+		return v
+	}
+	if meta.Type != KindHashMap {
+		panic("metadata must be a hash-map or nil")
+	}
+	v.Meta = &meta
+	return v
+}
+
+// mergeMeta returns v with the entries of meta added to its existing metadata.
+func mergeMeta(v Value, meta Value) Value {
+	if v.Meta == nil {
+		return withMeta(v, meta)
+	}
+	kv := copyHashMap(*v.Meta)
+	for k, entry := range meta.Val.(map[string]MapEntry) {
+		kv[k] = entry
+	}
+	return withMeta(v, Value{Type: KindHashMap, Val: kv})
+}
+
+// getMeta returns the metadata of v or nil.
+func getMeta(v Value) Value {
+	if v.Meta == nil {
+		return Value{Type: KindNil, Val: nil}
+	}
+	return *v.Meta
+}
@@ -0,0 +1,225 @@
+package malarkey
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Numeric literals. Digits may be separated by single underscores.
+// * integer - 42, -0xFF, 0o17, 0b1010, 36rZZ, 1_000_000. literals that do not fit in an int64 read as bigints
+// * bigint  - 123N, 0xFFN
+// * ratio   - 1/3. ratios with a denominator of 1 read as integers
+// * float   - 1.5, 1e10, 1.5e-3
+var (
+	intRegex   = regexp.MustCompile(`^([+-]?)(0[xX][0-9a-fA-F](?:_?[0-9a-fA-F])*|0[oO][0-7](?:_?[0-7])*|0[bB][01](?:_?[01])*|[0-9](?:_?[0-9])*)(N?)$`)
+	radixRegex = regexp.MustCompile(`^([+-]?)([0-9]{1,2})[rR]([0-9a-zA-Z](?:_?[0-9a-zA-Z])*)$`)
+	ratioRegex = regexp.MustCompile(`^([+-]?[0-9](?:_?[0-9])*)/([0-9](?:_?[0-9])*)$`)
+	floatRegex = regexp.MustCompile(`^[+-]?[0-9](?:_?[0-9])*(?:\.(?:[0-9](?:_?[0-9])*)?)?(?:[eE][+-]?[0-9]+)?$`)
+)
+
+// parseNumber parses a numeric literal. ok is false if the token does not start like a number, i.e. it is an optional
+// sign followed by a digit. Tokens that start like a number but are malformed are errors.
+func parseNumber(token string) (v Value, ok bool, err error) {
+	if i, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return Value{Type: KindInteger, Val: i}, true, nil
+	}
+	digits := strings.TrimLeft(token, "+-")
+	if len(token)-len(digits) > 1 || digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return Value{}, false, nil
+	}
+
+	if m := intRegex.FindStringSubmatch(token); m != nil {
+		digits, base := m[2], 10
+		if len(digits) > 1 && digits[0] == '0' {
+			switch digits[1] {
+			case 'x', 'X':
+				digits, base = digits[2:], 16
+			case 'o', 'O':
+				digits, base = digits[2:], 8
+			case 'b', 'B':
+				digits, base = digits[2:], 2
+			}
+		}
+		return parseInteger(m[1], digits, base, m[3] == "N")
+	}
+	if m := radixRegex.FindStringSubmatch(token); m != nil {
+		base, _ := strconv.Atoi(m[2])
+		if base < 2 || base > 36 {
+			return Value{}, true, fmt.Errorf("invalid number %s: radix must be between 2 and 36", token)
+		}
+		return parseInteger(m[1], m[3], base, false)
+	}
+	if m := ratioRegex.FindStringSubmatch(token); m != nil {
+		r, ok := new(big.Rat).SetString(strings.ReplaceAll(m[1], "_", "") + "/" + strings.ReplaceAll(m[2], "_", ""))
+		if !ok {
+			return Value{}, true, fmt.Errorf("invalid number %s: divide by zero", token)
+		}
+		return normalizeRatio(r), true, nil
+	}
+	if floatRegex.MatchString(token) {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64); err == nil {
+			return Value{Type: KindFloat, Val: f}, true, nil
+		}
+	}
+	return Value{}, true, fmt.Errorf("invalid number %s", token)
+}
+
+// parseInteger parses integer digits in a base. It is a bigint if forced or if it does not fit in an int64.
+func parseInteger(sign, digits string, base int, forceBig bool) (Value, bool, error) {
+	i, ok := new(big.Int).SetString(strings.ReplaceAll(digits, "_", ""), base)
+	if !ok {
+		return Value{}, true, fmt.Errorf("invalid number %s%s", sign, digits)
+	}
+	if sign == "-" {
+		i.Neg(i)
+	}
+	if !forceBig && i.IsInt64() {
+		return Value{Type: KindInteger, Val: i.Int64()}, true, nil
+	}
+	return Value{Type: KindBigInt, Val: i}, true, nil
+}
+
+// normalizeRatio returns a ratio, or an integer if its denominator is 1.
+func normalizeRatio(r *big.Rat) Value {
+	if !r.IsInt() {
+		return Value{Type: KindRatio, Val: r}
+	}
+	if r.Num().IsInt64() {
+		return Value{Type: KindInteger, Val: r.Num().Int64()}
+	}
+	return Value{Type: KindBigInt, Val: new(big.Int).Set(r.Num())}
+}
+
+// numeric contagion order. an operation on two numbers produces the type of the higher ranked one.
+var numberRanks = map[Kind]int{KindInteger: 0, KindBigInt: 1, KindRatio: 2, KindFloat: 3}
+
+func isNumber(v Value) bool {
+	_, ok := numberRanks[v.Type]
+	return ok
+}
+
+func toBigInt(v Value) *big.Int {
+	if v.Type == KindInteger {
+		return big.NewInt(v.Int())
+	}
+	return v.Val.(*big.Int)
+}
+
+func toRat(v Value) *big.Rat {
+	switch v.Type {
+	case KindInteger:
+		return new(big.Rat).SetInt64(v.Int())
+	case KindBigInt:
+		return new(big.Rat).SetInt(v.Val.(*big.Int))
+	}
+	return v.Val.(*big.Rat)
+}
+
+// arith applies an arithmetic operator (+, -, * or /) to two numbers. Division of integers and bigints truncates.
+func arith(op string, a, b Value) Value {
+	if !isNumber(a) || !isNumber(b) {
+		panic(fmt.Sprintf("%s requires numbers", op))
+	}
+	rank := numberRanks[a.Type]
+	if numberRanks[b.Type] > rank {
+		rank = numberRanks[b.Type]
+	}
+	switch rank {
+	case 0:
+		x, y := a.Int(), b.Int()
+		switch op {
+		case "+":
+			return Value{Type: KindInteger, Val: x + y}
+		case "-":
+			return Value{Type: KindInteger, Val: x - y}
+		case "*":
+			return Value{Type: KindInteger, Val: x * y}
+		}
+		if y == 0 {
+			panic("divide by zero")
+		}
+		return Value{Type: KindInteger, Val: x / y}
+	case 1:
+		x, y, out := toBigInt(a), toBigInt(b), new(big.Int)
+		switch op {
+		case "+":
+			out.Add(x, y)
+		case "-":
+			out.Sub(x, y)
+		case "*":
+			out.Mul(x, y)
+		default:
+			if y.Sign() == 0 {
+				panic("divide by zero")
+			}
+			out.Quo(x, y)
+		}
+		return Value{Type: KindBigInt, Val: out}
+	case 2:
+		x, y, out := toRat(a), toRat(b), new(big.Rat)
+		switch op {
+		case "+":
+			out.Add(x, y)
+		case "-":
+			out.Sub(x, y)
+		case "*":
+			out.Mul(x, y)
+		default:
+			if y.Sign() == 0 {
+				panic("divide by zero")
+			}
+			out.Quo(x, y)
+		}
+		return normalizeRatio(out)
+	default:
+		x, y := asFloat(a.Val), asFloat(b.Val)
+		switch op {
+		case "+":
+			return Value{Type: KindFloat, Val: x + y}
+		case "-":
+			return Value{Type: KindFloat, Val: x - y}
+		case "*":
+			return Value{Type: KindFloat, Val: x * y}
+		}
+		return Value{Type: KindFloat, Val: x / y}
+	}
+}
+
+// compareNumbers returns -1, 0 or 1 if a is less than, equal to or greater than b.
+func compareNumbers(a, b Value) int {
+	if !isNumber(a) || !isNumber(b) {
+		panic("cannot compare non-numbers")
+	}
+	if a.Type == KindFloat || b.Type == KindFloat {
+		x, y := asFloat(a.Val), asFloat(b.Val)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	if a.Type == KindInteger && b.Type == KindInteger {
+		x, y := a.Int(), b.Int()
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return toRat(a).Cmp(toRat(b))
+}
+
+// printNumber returns the literal of a bigint or ratio. bigints print with an N suffix when readable.
+func printNumber(v Value, readably bool) string {
+	if v.Type == KindBigInt && readably {
+		return v.Val.(*big.Int).String() + "N"
+	}
+	return fmt.Sprintf("%v", v.Val)
+}
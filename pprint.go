@@ -0,0 +1,199 @@
+package malarkey
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// forms laid out like hand-written code: the head and this many arguments stay on the first line and the remaining
+// body forms are indented by 2.
+var prettyBodyForms = map[string]int{"def!": 1, "defmacro!": 1, "fn*": 1, "let*": 1, "if": 1, "do": 0, "try*": 0, "catch*": 1}
+
+// PrettyPrint returns the readable representation of a value, breaking collections that do not fit in width columns
+// across lines. Collections put one element per line, or fill lines if they only hold scalars. Hash-maps put one entry
+// per line and code forms such as let*, fn*, if and defmacro! indent their bodies.
+func PrettyPrint(v Value, width int) string {
+	return prettyPrint(v, width, nil)
+}
+
+// prettyPrint pretty prints with the printers of an env in addition to the ones registered by RegisterPrinter.
+func prettyPrint(v Value, width int, printers map[string]func(Value, bool) string) string {
+	p := &prettyPrinter{width: width, opts: PrintOptions{Readably: true, Printers: printers}}
+	p.print(v)
+	return p.b.String()
+}
+
+type prettyPrinter struct {
+	b     strings.Builder
+	width int
+	col   int // 0-indexed column of the next write
+	opts  PrintOptions
+}
+
+// flat returns the representation of a value on one line.
+func (p *prettyPrinter) flat(v Value) string {
+	return Sprint(v, p.opts)
+}
+
+func (p *prettyPrinter) write(s string) {
+	p.b.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.col = utf8.RuneCountInString(s[i+1:])
+	} else {
+		p.col += utf8.RuneCountInString(s)
+	}
+}
+
+// newline starts a new line indented to col.
+func (p *prettyPrinter) newline(col int) {
+	p.write("\n" + strings.Repeat(" ", col))
+}
+
+// fits returns true if s fits on the current line.
+func (p *prettyPrinter) fits(s string) bool {
+	return p.col+utf8.RuneCountInString(s) <= p.width
+}
+
+// print writes a value at the current column.
+func (p *prettyPrinter) print(v Value) {
+	flat := p.flat(v)
+	if p.fits(flat) {
+		p.write(flat)
+		return
+	}
+	switch v.Type {
+	case KindList:
+		p.printList(v.Elems())
+	case KindVector:
+		p.printElems("[", v.Elems(), "]")
+	case KindSet:
+		p.printElems("#{", sortedSetElems(v, 0), "}")
+	case KindHashMap:
+		p.printMap(v)
+	default:
+		p.write(flat)
+	}
+}
+
+// printElems writes the elements of a collection aligned after the opening delimiter. Collections of scalars fill
+// each line with as many elements as fit. Otherwise elements go one per line.
+func (p *prettyPrinter) printElems(open string, elems []Value, close string) {
+	fill := true
+	for _, elem := range elems {
+		if isCollection(elem) {
+			fill = false
+		}
+	}
+	p.write(open)
+	col := p.col
+	for i, elem := range elems {
+		switch {
+		case i == 0:
+		case fill && i < len(elems)-1 && p.fits(" "+p.flat(elem)),
+			fill && p.fits(" "+p.flat(elem)+close):
+			p.write(" ")
+		default:
+			p.newline(col)
+		}
+		p.print(elem)
+	}
+	p.write(close)
+}
+
+// printList writes a list as code. Body forms indent their bodies by 2. Other calls align their arguments with the
+// first argument.
+func (p *prettyPrinter) printList(elems []Value) {
+	start := p.col
+	if len(elems) == 0 || elems[0].Type != KindSymbol {
+		p.printElems("(", elems, ")")
+		return
+	}
+	head := elems[0]
+	p.write("(")
+	p.print(head)
+
+	n, isBody := prettyBodyForms[head.Str()]
+	if !isBody {
+		argCol := p.col + 1
+		for i, arg := range elems[1:] {
+			if i == 0 {
+				p.write(" ")
+			} else {
+				p.newline(argCol)
+			}
+			p.print(arg)
+		}
+		p.write(")")
+		return
+	}
+
+	for i, arg := range elems[1:] {
+		switch {
+		case i < n && head.Val == "let*" && arg.Type == KindVector:
+			p.write(" ")
+			p.printBindings(arg.Elems())
+		case i < n:
+			p.write(" ")
+			p.print(arg)
+		default:
+			p.newline(start + 2)
+			p.print(arg)
+		}
+	}
+	p.write(")")
+}
+
+// printBindings writes let* bindings with one name and value pair per line.
+func (p *prettyPrinter) printBindings(bindings []Value) {
+	flat := p.flat(Value{Type: KindVector, Val: bindings})
+	if p.fits(flat) {
+		p.write(flat)
+		return
+	}
+	p.write("[")
+	col := p.col
+	for i := 0; i < len(bindings); i += 2 {
+		if i > 0 {
+			p.newline(col)
+		}
+		p.print(bindings[i])
+		if i+1 < len(bindings) {
+			p.printValue(bindings[i+1], col)
+		}
+	}
+	p.write("]")
+}
+
+// printMap writes a hash-map with one entry per line, in the order of Print.
+func (p *prettyPrinter) printMap(m Value) {
+	p.write("{")
+	col := p.col
+	for i, entry := range sortedMapEntries(m, 0) {
+		if i > 0 {
+			p.newline(col)
+		}
+		p.print(entry.Key)
+		p.printValue(entry.Val, col)
+	}
+	p.write("}")
+}
+
+// printValue writes the value of a map entry or binding after its key. Values that do not fit after the key go on the
+// next line, indented from the key's column col.
+func (p *prettyPrinter) printValue(v Value, col int) {
+	flat := p.flat(v)
+	if !p.fits(" "+flat) && !isCollection(v) {
+		p.newline(col + 1)
+	} else {
+		p.write(" ")
+	}
+	p.print(v)
+}
+
+func isCollection(v Value) bool {
+	switch v.Type {
+	case KindList, KindVector, KindHashMap, KindSet:
+		return true
+	}
+	return false
+}
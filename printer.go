@@ -1,47 +1,393 @@
 package malarkey
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Print returns a string representation of the given value.
+// escapeString returns the readable string literal of s. Non-printable runes are \u escaped and bytes that are not
+// valid UTF-8 are \x escaped so that reading the literal always returns s.
+func escapeString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+			i++
+			continue
+		}
+		i += size
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case 0:
+			b.WriteString(`\0`)
+		default:
+			switch {
+			case unicode.IsPrint(r):
+				b.WriteRune(r)
+			case r <= 0xffff:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			default:
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// printers registered by RegisterPrinter. the map is replaced rather than modified so that printing can read it
+// without locking.
+var (
+	printersMu sync.Mutex
+	printers   atomic.Pointer[map[string]func(v Value, readably bool) string]
+)
+
+// RegisterPrinter registers the printer of a value type, replacing how Print, Fprint and the printing builtins print
+// values of the type in both readable and display modes. A type of "#tag" only applies to tagged-literal values with
+// that tag. Registering a nil printer restores the default printing of the type. Printers defined with print-method in
+// an env take precedence in that env.
+func RegisterPrinter(typ string, fn func(v Value, readably bool) string) {
+	printersMu.Lock()
+	defer printersMu.Unlock()
+	next := map[string]func(v Value, readably bool) string{}
+	if cur := printers.Load(); cur != nil {
+		for k, v := range *cur {
+			next[k] = v
+		}
+	}
+	if fn == nil {
+		delete(next, typ)
+	} else {
+		next[typ] = fn
+	}
+	printers.Store(&next)
+}
+
+// lookupPrinter returns the printer of a value in a printer table. Printers of a tag take precedence over the printer
+// of tagged-literal.
+func lookupPrinter(table map[string]func(Value, bool) string, v Value) (func(Value, bool) string, bool) {
+	if len(table) == 0 {
+		return nil, false
+	}
+	if v.Type == KindTaggedLiteral {
+		if fn, ok := table["#"+v.Val.(TaggedLiteral).Tag]; ok {
+			return fn, true
+		}
+	}
+	fn, ok := table[v.Type.String()]
+	return fn, ok
+}
+
+// builtinNames maps the code pointers of builtin functions to their names. Builtins are closures of the same function
+// literals in every env, so the names only need to be recorded once.
+var (
+	builtinNames     map[uintptr]string
+	builtinNamesOnce sync.Once
+)
+
+// fnName returns the name of a function, or "" if it is anonymous.
+func fnName(v Value) string {
+	if v.Type == KindFunctionTCO {
+		return v.Val.(FunctionTCO).Name
+	}
+	return builtinNames[reflect.ValueOf(v.Val).Pointer()]
+}
+
+// printFn returns the representation of a function, e.g. `#<fn my-fn [a b & more] file.mal:12>` or `#<macro cond>`.
+func printFn(v Value) string {
+	var parts []string
+	if v.Type == KindFunctionTCO && v.Val.(FunctionTCO).IsMacro {
+		parts = append(parts, "#<macro")
+	} else {
+		parts = append(parts, "#<fn")
+	}
+	if name := fnName(v); name != "" {
+		parts = append(parts, name)
+	}
+	if v.Type == KindFunctionTCO && !v.Val.(FunctionTCO).IsMacro {
+		f := v.Val.(FunctionTCO)
+		parts = append(parts, Print(Value{Type: KindVector, Val: f.Params}, true))
+		if f.Span != nil && f.Span.File != "" {
+			parts = append(parts, fmt.Sprintf("%s:%d", f.Span.File, f.Span.Start.Line))
+		}
+	}
+	return strings.Join(parts, " ") + ">"
+}
+
+// PrintOptions control how a value is printed.
+type PrintOptions struct {
+	Readably  bool // print strings, chars and other scalars as literals that read back as the same value
+	MaxLength int  // elements printed per collection before the rest is elided as "...". 0 is unlimited
+	MaxLevel  int  // depth of nested collections printed before deeper ones are elided as "...". 0 is unlimited
+	EDN       bool // print readably as EDN. values with no EDN representation are errors
+	// Printers by type, as in RegisterPrinter. They take precedence over the printers registered with RegisterPrinter.
+	Printers map[string]func(v Value, readably bool) string
+}
+
+// Print returns a string representation of the given value. It uses the printers registered with RegisterPrinter; use
+// Env.Print to also honor print-method, *print-length* and *print-level* in an env.
 func Print(s Value, readably bool) string {
+	return Sprint(s, PrintOptions{Readably: readably})
+}
+
+// Sprint returns a string representation of the given value.
+func Sprint(v Value, opts PrintOptions) string {
+	var b strings.Builder
+	p := newPrinter(&b, opts)
+	p.print(v, 0)
+	return b.String()
+}
+
+// Fprint writes the representation of a value to w as it is printed instead of building it in memory. Printing
+// stops at the first write error.
+func Fprint(w io.Writer, v Value, opts PrintOptions) error {
+	bw := bufio.NewWriter(w)
+	p := newPrinter(bw, opts)
+	p.print(v, 0)
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+type printer struct {
+	w        io.StringWriter
+	opts     PrintOptions
+	err      error
+	atomPath map[*Atom]bool                      // the atoms being printed. an atom that contains itself is a cycle
+	global   map[string]func(Value, bool) string // RegisterPrinter printers when printing started
+}
+
+func newPrinter(w io.StringWriter, opts PrintOptions) *printer {
+	p := &printer{w: w, opts: opts, atomPath: map[*Atom]bool{}}
+	if global := printers.Load(); global != nil {
+		p.global = *global
+	}
+	return p
+}
+
+func (p *printer) write(s string) {
+	if p.err == nil {
+		_, p.err = p.w.WriteString(s)
+	}
+}
+
+// sortedMapEntries returns the entries of a hash-map that fit in the print length, and one more to elide if there
+// are more.
+func (p *printer) sortedMapEntries(m Value) []MapEntry {
+	if p.opts.MaxLength > 0 {
+		return sortedMapEntries(m, p.opts.MaxLength+1)
+	}
+	return sortedMapEntries(m, 0)
+}
+
+// sortedSetElems returns the elements of a set that fit in the print length, like sortedMapEntries.
+func (p *printer) sortedSetElems(s Value) []Value {
+	if p.opts.MaxLength > 0 {
+		return sortedSetElems(s, p.opts.MaxLength+1)
+	}
+	return sortedSetElems(s, 0)
+}
+
+// elided returns true after writing "..." if a collection at level is nested too deeply to be printed.
+func (p *printer) elided(level int) bool {
+	if p.opts.MaxLevel > 0 && level >= p.opts.MaxLevel {
+		p.write("...")
+		return true
+	}
+	return false
+}
+
+// truncated returns true after writing "..." if the element at index i of a collection is past the print length.
+func (p *printer) truncated(i int) bool {
+	if p.opts.MaxLength > 0 && i >= p.opts.MaxLength {
+		p.write("...")
+		return true
+	}
+	return false
+}
+
+// print writes a value nested in level collections.
+func (p *printer) print(s Value, level int) {
+	readably := p.opts.Readably || p.opts.EDN
+	if fn, ok := lookupPrinter(p.opts.Printers, s); ok {
+		p.write(fn(s, readably))
+		return
+	}
+	if fn, ok := lookupPrinter(p.global, s); ok {
+		p.write(fn(s, readably))
+		return
+	}
+	if p.opts.EDN && (s.Type == KindFunction || s.Type == KindFunctionTCO || s.Type == KindAtom || s.Type == KindRegex ||
+		s.Type == KindRatio) {
+		if p.err == nil {
+			p.err = fmt.Errorf("cannot write %s as EDN", s.Type)
+		}
+		return
+	}
 	switch s.Type {
-	case "string":
-		str := s.Val.(string)
+	case KindString:
+		str := s.Str()
+		if p.opts.EDN {
+			str = ednString(str)
+		} else if readably {
+			str = escapeString(str)
+		}
+		p.write(str)
+	case KindChar:
+		if p.opts.EDN {
+			p.write(ednChar(s.Val.(rune)))
+		} else if readably {
+			p.write(printChar(s.Val.(rune)))
+		} else {
+			p.write(string(s.Val.(rune)))
+		}
+	case KindBigInt, KindRatio:
+		p.write(printNumber(s, readably))
+	case KindRegex:
+		if readably {
+			p.write(fmt.Sprintf(`#"%s"`, s.Val.(*regexp.Regexp)))
+		} else {
+			p.write(s.Val.(*regexp.Regexp).String())
+		}
+	case KindInst:
 		if readably {
-			str = strings.Replace(str, "\\", "\\\\", -1)
-			str = strings.Replace(str, "\"", "\\\"", -1)
-			str = strings.Replace(str, "\n", "\\n", -1)
-			return fmt.Sprintf("\"%s\"", str)
-		}
-		return str
-	case "symbol", "integer", "float", "boolean", "keyword":
-		return fmt.Sprintf("%v", s.Val)
-	case "nil":
-		return "nil"
-	case "list", "vector":
-		var elements []string
-		for _, element := range s.Val.([]Value) {
-			elements = append(elements, Print(element, readably))
-		}
-		if s.Type == "list" {
-			return fmt.Sprintf("(%s)", strings.Join(elements, " "))
-		}
-		return fmt.Sprintf("[%s]", strings.Join(elements, " "))
-	case "hash-map":
-		var elements []string
-		for k, v := range s.Val.(map[string]Value) {
-			elements = append(elements, fmt.Sprintf("\"%s\"", k), Print(v, readably))
-		}
-		return fmt.Sprintf("{%s}", strings.Join(elements, " "))
-	case "function", "function-tco":
-		return "#<function>"
-	case "atom":
-		id := s.Val.(int)
-		return fmt.Sprintf("(atom %s)", Print(atoms[id], readably))
+			p.write(fmt.Sprintf(`#inst "%s"`, printInst(s.Val.(time.Time))))
+		} else {
+			p.write(printInst(s.Val.(time.Time)))
+		}
+	case KindUUID:
+		if readably {
+			p.write(fmt.Sprintf(`#uuid "%s"`, s.Val))
+		} else {
+			p.write(s.Str())
+		}
+	case KindTaggedLiteral:
+		tl := s.Val.(TaggedLiteral)
+		p.write(fmt.Sprintf("#%s ", tl.Tag))
+		p.print(tl.Form, level)
+	case KindSymbol, KindKeyword:
+		p.write(s.Str())
+	case KindInteger:
+		p.write(strconv.FormatInt(s.Int(), 10))
+	case KindFloat:
+		// readable floats always have a decimal point or exponent so that they read back as floats. infinities and NaN
+		// have no literal
+		f, err := ednFloat(s.Float())
+		if err != nil {
+			if p.opts.EDN && p.err == nil {
+				p.err = err
+			}
+			f = fmt.Sprintf("%v", s.Val)
+		} else if !readably {
+			f = fmt.Sprintf("%v", s.Val)
+		}
+		p.write(f)
+	case KindBoolean:
+		p.write(fmt.Sprintf("%v", s.Val))
+	case KindNil:
+		p.write("nil")
+	case KindList, KindVector:
+		if p.elided(level) {
+			return
+		}
+		open, close := "(", ")"
+		if s.Type == KindVector {
+			open, close = "[", "]"
+		}
+		p.write(open)
+		for i, element := range s.Elems() {
+			if i > 0 {
+				p.write(" ")
+			}
+			if p.truncated(i) || p.err != nil {
+				break
+			}
+			p.print(element, level+1)
+		}
+		p.write(close)
+	case KindHashMap:
+		if p.elided(level) {
+			return
+		}
+		// entries are sorted by the hashKey of their keys for a stable output that can stop at the print length
+		p.write("{")
+		for i, entry := range p.sortedMapEntries(s) {
+			if i > 0 {
+				p.write(" ")
+			}
+			if p.truncated(i) || p.err != nil {
+				break
+			}
+			p.print(entry.Key, level+1)
+			p.write(" ")
+			p.print(entry.Val, level+1)
+		}
+		p.write("}")
+	case KindSet:
+		if p.elided(level) {
+			return
+		}
+		p.write("#{")
+		for i, elem := range p.sortedSetElems(s) {
+			if i > 0 {
+				p.write(" ")
+			}
+			if p.truncated(i) || p.err != nil {
+				break
+			}
+			p.print(elem, level+1)
+		}
+		p.write("}")
+	case KindFunction, KindFunctionTCO:
+		p.write(printFn(s))
+	case KindAtom:
+		atom := s.Val.(*Atom)
+		if p.atomPath[atom] {
+			p.write(fmt.Sprintf("#<atom %d ...>", atom.ID))
+			return
+		}
+		if p.elided(level) {
+			return
+		}
+		p.atomPath[atom] = true
+		defer delete(p.atomPath, atom)
+		p.write("(atom ")
+		p.print(atom.Val, level+1)
+		p.write(")")
 	default:
-		panic(fmt.Sprintf("cannot print unsupported type: %s", s.Type))
+		// types of embedding programs without a registered printer
+		if p.opts.EDN {
+			if p.err == nil {
+				p.err = fmt.Errorf("cannot write %s as EDN", s.Type)
+			}
+			return
+		}
+		p.write(fmt.Sprintf("#<%s>", s.Type))
 	}
 }
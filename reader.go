@@ -1,134 +1,641 @@
 package malarkey
 
 import (
-	"regexp"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var tokenRegex = regexp.MustCompile(`[\s,]*(~@|[\[\]{}()'` + "`" + `~^@]|"(?:\\.|[^\\"])*"?|;.*|[^\s\[\]{}('"` + "`" + `,;)]*)`)
-
-// Reader reads tokens.
+// Reader reads forms from a stream of tokens. The source is lexed incrementally as tokens are consumed.
 type Reader struct {
-	Tokens   []string
-	Position int
+	File     string   // file name recorded in the spans of read forms and in syntax errors. optional
+	Features []string // feature keywords selected by reader conditionals. defaults to the host language's feature
+	// DataReader looks up the data reader of a tagged literal before the registry of RegisterDataReader. optional
+	DataReader func(tag string) (func(Value) Value, bool)
+	Limits     Limits // bounds for reading untrusted input. unlimited by default
+	EDN        bool   // only read EDN, rejecting the reader macros of code
+
+	lexer  *Lexer
+	peek   Token
+	peeked bool
+	inFn   bool // reading the body of a #() literal
+	depth  int  // nesting depth of the form being read
+	tokens int  // number of tokens read
+}
+
+// NewReader creates a Reader that reads forms from r. file is recorded in the spans of read forms and may be empty.
+func NewReader(r io.Reader, file string) *Reader {
+	return &Reader{File: file, lexer: NewLexer(r, file)}
 }
 
 // Next returns the next token and advances the reader.
-func (r *Reader) Next() string {
-	if r.Position >= len(r.Tokens) {
-		return ""
-	}
-	r.Position++
-	return r.Tokens[r.Position-1]
+func (r *Reader) Next() Token {
+	token := r.Peek()
+	r.peeked = false
+	return token
 }
 
 // Peek returns the next token without advancing the reader.
-func (r *Reader) Peek() string {
-	if r.Position >= len(r.Tokens) {
-		return ""
-	}
-	return r.Tokens[r.Position]
-}
-
-// Tokenize splits a input text into tokens.
-func Tokenize(input string) []string {
-	matches := tokenRegex.FindAllStringSubmatch(input, -1)
-	var out []string
-	for _, match := range matches {
-		// note: regex does not drop leading whitespaces and commas. trim until no change
-		var cur string
-		trimmed := match[0]
-		for trimmed != cur {
-			cur = trimmed
-			trimmed = strings.Trim(strings.TrimSpace(cur), ",")
-		}
-		if cur == "" || strings.HasPrefix(cur, ";") {
-			continue
+func (r *Reader) Peek() Token {
+	if !r.peeked {
+		r.lexer.MaxBytes = r.Limits.MaxBytes
+		token, err := r.lexer.Next()
+		if err != nil {
+			panic(err)
+		}
+		if token.Kind != TokenEOF && token.Kind != TokenWhitespace && token.Kind != TokenComment {
+			r.tokens++
+			if r.Limits.MaxTokens > 0 && r.tokens > r.Limits.MaxTokens {
+				panic(&LimitError{File: r.File, Pos: token.Start, Limit: "tokens", Max: r.Limits.MaxTokens})
+			}
+		}
+		r.peek, r.peeked = token, true
+	}
+	return r.peek
+}
+
+// recoverErr recovers a panic of a read into *err. It must be deferred directly.
+func recoverErr(err *error) {
+	if rec := recover(); rec != nil {
+		switch rec := rec.(type) {
+		case error:
+			*err = rec
+		default:
+			*err = fmt.Errorf("%v", rec)
+		}
+	}
+}
+
+// ReadForm reads the next top-level form. It returns io.EOF when there are no more forms.
+func (r *Reader) ReadForm() (form Value, err error) {
+	defer recoverErr(&err)
+	for {
+		if r.Peek().Kind == TokenEOF {
+			return Value{}, io.EOF
+		}
+		if form, ok := readOptionalForm(r); ok {
+			return form, nil
+		}
+	}
+}
+
+// hasFeature returns true if a reader conditional feature keyword is selected.
+func (r *Reader) hasFeature(feature string) bool {
+	if feature == ":default" {
+		return true
+	}
+	if r.Features == nil {
+		return feature == hostFeature(HostLanguage)
+	}
+	for _, f := range r.Features {
+		if f == feature {
+			return true
 		}
-		out = append(out, cur)
 	}
-	return out
+	return false
+}
+
+// hostFeature returns the reader conditional feature keyword of a host language, e.g. :malarkey for "Mal-arkey".
+func hostFeature(language string) string {
+	return ":" + strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(language))
+}
+
+// nest enters a form opened by token, panicking with a *LimitError past Limits.MaxDepth. It returns the function that
+// leaves the form.
+func (r *Reader) nest(token Token) func() {
+	r.depth++
+	if r.Limits.MaxDepth > 0 && r.depth > r.Limits.MaxDepth {
+		panic(&LimitError{File: r.File, Pos: token.Start, Limit: "depth", Max: r.Limits.MaxDepth})
+	}
+	return func() { r.depth-- }
+}
+
+// span returns the span from the start of one token to the end of another.
+func (r *Reader) span(start, end Token) *Span {
+	return &Span{File: r.File, Start: start.Start, End: end.End}
+}
+
+// errorf panics with a *SyntaxError at pos.
+func (r *Reader) errorf(pos Pos, format string, args ...interface{}) {
+	panic(&SyntaxError{File: r.File, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// incompletef panics with a *SyntaxError at pos for input that ended before a form was complete.
+func (r *Reader) incompletef(pos Pos, format string, args ...interface{}) {
+	panic(&SyntaxError{File: r.File, Pos: pos, Msg: fmt.Sprintf(format, args...), Incomplete: true})
 }
 
 // Read parses input text into an AST.
 func Read(input string) Value {
-	reader := &Reader{Tokens: Tokenize(input)}
+	return ReadFile(input, "")
+}
+
+// ReadFile parses input text into an AST, recording file as the source file of the spans of read forms.
+func ReadFile(input, file string) Value {
+	return readOnly(NewReader(strings.NewReader(input), file))
+}
+
+// readOnly reads a form that must be the only form in the reader.
+func readOnly(reader *Reader) Value {
 	s := readForm(reader)
-	if reader.Peek() != "" {
-		panic("invalid trailing tokens")
+	if token := reader.Peek(); token.Kind != TokenEOF {
+		reader.errorf(token.Start, "unexpected %s after form at %s", token.Text, token.Start)
 	}
 	return s
 }
 
+// ReadStatus is the outcome of ReadPartial.
+type ReadStatus int
+
+// Read statuses.
+const (
+	ReadComplete   ReadStatus = iota // all input was read as complete forms
+	ReadIncomplete                   // the input ends inside an unfinished form, e.g. an open collection or string
+	ReadInvalid                      // the input has a syntax error
+)
+
+func (s ReadStatus) String() string {
+	return [...]string{"complete", "incomplete", "invalid"}[s]
+}
+
+// ReadPartial reads all complete top-level forms from src, telling apart input that is complete, unfinished or
+// invalid. rest is the unread source text starting at the unfinished or invalid form, and err is the syntax error of
+// invalid input. Front ends can wait for more input while the status is ReadIncomplete, appending it to rest.
+func ReadPartial(src string) (forms []Value, rest string, status ReadStatus, err error) {
+	reader := NewReader(strings.NewReader(src), "")
+	for {
+		// no token is peeked between forms, so this is the offset just after the last form
+		offset := reader.lexer.pos.Offset
+		form, err := reader.ReadForm()
+		if err == io.EOF {
+			return forms, "", ReadComplete, nil
+		}
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Incomplete {
+			return forms, src[offset:], ReadIncomplete, nil
+		}
+		if err != nil {
+			return forms, src[offset:], ReadInvalid, err
+		}
+		forms = append(forms, form)
+	}
+}
+
+// closeTokens are the closing tokens of opening tokens.
+var closeTokens = map[string]string{"(": ")", "[": "]", "{": "}", "#{": "}", "#(": ")"}
+
 func readCollection(reader *Reader, peeked string) Value {
-	stopToken := map[string]string{"(": ")", "[": "]", "{": "}"}[peeked]
-	seqType := map[string]string{"(": "list", "[": "vector", "{": "hash-map"}[peeked]
+	stopToken := closeTokens[peeked]
+	seqType := map[string]Kind{"(": KindList, "[": KindVector, "{": KindHashMap, "#{": KindSet, "#(": KindList}[peeked]
 
-	reader.Next()
+	open := reader.Next()
 	var elements []Value
-	for reader.Peek() != stopToken {
-		elements = append(elements, readForm(reader))
+	for {
+		token := reader.Peek()
+		if token.Kind == TokenEOF {
+			reader.incompletef(open.Start, "unmatched %s opened at %s", open.Text, open.Start)
+		}
+		if token.Kind == TokenClose && token.Text != stopToken {
+			reader.Next()
+			reader.errorf(open.Start, "unmatched %s opened at %s", open.Text, open.Start)
+		}
+		if token.Kind == TokenClose {
+			break
+		}
+		forms, _ := readForms(reader)
+		elements = append(elements, forms...)
 	}
-	reader.Next()
+	span := reader.span(open, reader.Next())
 
-	if seqType == "hash-map" {
-		kv := map[string]Value{}
-		for i := 0; i < len(elements); i += 2 {
-			kv[elements[i].Val.(string)] = elements[i+1]
+	if seqType == KindHashMap {
+		if len(elements)%2 != 0 {
+			reader.errorf(open.Start, "map literal opened at %s must contain an even number of forms", open.Start)
 		}
-		return Value{Type: "hash-map", Val: kv}
+		m := newHashMap(elements)
+		if len(m.Val.(map[string]MapEntry)) != len(elements)/2 {
+			reader.errorf(open.Start, "map literal opened at %s contains duplicate keys", open.Start)
+		}
+		m.Span = span
+		return m
+	}
+	if seqType == KindSet {
+		set := newSet(elements)
+		if len(set.Val.(map[string]Value)) != len(elements) {
+			reader.errorf(open.Start, "set literal opened at %s contains duplicate elements", open.Start)
+		}
+		set.Span = span
+		return set
 	}
-	return Value{Type: seqType, Val: elements}
+	return Value{Type: seqType, Val: elements, Span: span}
 }
 
-// only currently supporting integers and symbols
+// readAtom parses a token that is a single value: a string, char, regex, number, keyword, boolean, nil or symbol.
 func readAtom(reader *Reader) Value {
 	token := reader.Next()
-	if token == "" {
-		panic("expected atom")
+	var v Value
+	var err error
+	switch token.Kind {
+	case TokenString:
+		v, err = parseString(token.Text)
+	case TokenChar:
+		v, err = parseChar(token.Text)
+	case TokenRegex:
+		v, err = parseRegex(token.Text)
+	default:
+		v, err = parseAtom(token.Text)
+	}
+	if err != nil {
+		reader.errorf(token.Start, "%s at %s", err, token.Start)
 	}
+	v.Span = reader.span(token, token)
+	return v
+}
+
+// string escapes other than \u
+var stringEscapes = map[rune]rune{'"': '"', '\\': '\\', 'n': '\n', 't': '\t', 'r': '\r', 'b': '\b', 'f': '\f', '0': 0}
 
-	if i, err := strconv.ParseInt(token, 10, 0); err == nil {
-		return Value{Type: "integer", Val: i}
+// parseString parses a string literal token. Raw strings, `"""..."""`, are taken as written. Other strings support the
+// escapes \" \\ \n \t \r \b \f \0, \uXXXX, \u{X...} and the byte escape \xNN.
+func parseString(token string) (Value, error) {
+	if strings.HasPrefix(token, `"""`) && len(token) >= 6 {
+		return Value{Type: KindString, Val: token[3 : len(token)-3]}, nil
 	}
-	if f, err := strconv.ParseFloat(token, 64); err == nil {
-		return Value{Type: "float", Val: f}
+
+	// escapes are ASCII, so the body is scanned by bytes, which also keeps invalid UTF-8 as written
+	var b strings.Builder
+	body := token[1 : len(token)-1]
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			b.WriteByte(body[i])
+			continue
+		}
+		i++
+		if r, ok := stringEscapes[rune(body[i])]; ok {
+			b.WriteRune(r)
+			continue
+		}
+		if body[i] == 'x' {
+			// \xNN is a byte, which escapeString uses for invalid UTF-8
+			if i+2 >= len(body) {
+				return Value{}, fmt.Errorf("invalid byte escape \\x%s in string", body[i+1:])
+			}
+			code, err := strconv.ParseUint(body[i+1:i+3], 16, 8)
+			if err != nil {
+				return Value{}, fmt.Errorf("invalid byte escape \\x%s in string", body[i+1:i+3])
+			}
+			b.WriteByte(byte(code))
+			i += 2
+			continue
+		}
+		if body[i] != 'u' {
+			r, _ := utf8.DecodeRuneInString(body[i:])
+			return Value{}, fmt.Errorf("invalid escape \\%c in string", r)
+		}
+		// \uXXXX or \u{X...}
+		var hex string
+		if i+1 < len(body) && body[i+1] == '{' {
+			end := i + 2
+			for end < len(body) && body[end] != '}' {
+				end++
+			}
+			if end == len(body) {
+				return Value{}, fmt.Errorf("unterminated \\u{ escape in string")
+			}
+			hex, i = body[i+2:end], end
+		} else if i+4 < len(body) {
+			hex, i = body[i+1:i+5], i+4
+		} else {
+			return Value{}, fmt.Errorf("invalid unicode escape \\u%s in string", body[i+1:])
+		}
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) > 6 || code > unicode.MaxRune {
+			return Value{}, fmt.Errorf("invalid unicode escape \\u%s in string", hex)
+		}
+		b.WriteRune(rune(code))
 	}
-	if strings.HasPrefix(token, "\"") {
-		str := token[1 : len(token)-1]
-		str = strings.Replace(str, "\\\"", "\"", -1)
-		str = strings.Replace(str, "\\n", "\n", -1)
-		str = strings.Replace(str, "\\\\", "\\", -1)
-		return Value{Type: "string", Val: str}
+	return Value{Type: KindString, Val: b.String()}, nil
+}
+
+func parseAtom(token string) (Value, error) {
+	if v, ok, err := parseNumber(token); ok {
+		return v, err
 	}
 	if strings.HasPrefix(token, ":") {
-		return Value{Type: "keyword", Val: token}
+		return Value{Type: KindKeyword, Val: token}, nil
 	}
 
 	switch token {
 	case "true":
-		return Value{Type: "boolean", Val: true}
+		return Value{Type: KindBoolean, Val: true}, nil
 	case "false":
-		return Value{Type: "boolean", Val: false}
+		return Value{Type: KindBoolean, Val: false}, nil
 	case "nil":
-		return Value{Type: "nil", Val: nil}
+		return Value{Type: KindNil, Val: nil}, nil
 	default:
-		return Value{Type: "symbol", Val: token}
+		return Value{Type: KindSymbol, Val: token}, nil
 	}
 }
 
 // readForm parses the next form from the reader.
-// Currently only supporting lists and atoms.
 func readForm(reader *Reader) Value {
+	for {
+		if form, ok := readOptionalForm(reader); ok {
+			return form
+		}
+	}
+}
+
+// readOptionalForm parses the next form from the reader if it does not read as nothing.
+func readOptionalForm(reader *Reader) (Value, bool) {
+	token := reader.Peek()
+	forms, spliced := readForms(reader)
+	if spliced {
+		reader.errorf(token.Start, "%s at %s can only splice into a collection", token.Text, token.Start)
+	}
+	if len(forms) == 0 {
+		return Value{}, false
+	}
+	return forms[0], true
+}
+
+// readForms parses the next form from the reader. A form can read as nothing (`#_` and reader conditionals with no
+// matching feature) or as many forms spliced into the enclosing collection (`#?@`).
+func readForms(reader *Reader) (forms []Value, spliced bool) {
+	token := reader.Peek()
+	if reader.EDN && !isEDNToken(token) {
+		reader.errorf(token.Start, "%s at %s is not valid EDN", token.Text, token.Start)
+	}
+	if token.Kind == TokenOpen || token.Kind == TokenMacro {
+		// every nested form is read through here, so this bounds the recursion
+		defer reader.nest(token)()
+	}
+	switch token.Text {
+	case "#_":
+		reader.Next()
+		readForm(reader)
+		return nil, false
+	case "#?", "#?@":
+		reader.Next()
+		return readConditional(reader, token), token.Text == "#?@"
+	}
+	return []Value{readPlainForm(reader)}, false
+}
+
+// readConditional reads the list of a `#?` or `#?@` reader conditional and returns the form of the first selected
+// feature. `#?@` returns the elements of the form to be spliced. The other branches are skipped without being parsed,
+// so they may hold syntax of other dialects, e.g. `#?(:clj 1.5M :default 2)`.
+func readConditional(reader *Reader, macro Token) []Value {
+	if reader.Peek().Kind == TokenEOF {
+		reader.incompletef(macro.Start, "unexpected end of input after %s at %s", macro.Text, macro.Start)
+	}
+	if reader.Peek().Text != "(" {
+		reader.errorf(macro.Start, "%s at %s must be followed by a list", macro.Text, macro.Start)
+	}
+	open := reader.Next()
+	var selected *Value
+	for {
+		token := reader.Next()
+		if token.Kind == TokenEOF {
+			reader.incompletef(open.Start, "unmatched %s opened at %s", open.Text, open.Start)
+		}
+		if token.Kind == TokenClose {
+			if token.Text != ")" {
+				reader.errorf(open.Start, "unmatched %s opened at %s", open.Text, open.Start)
+			}
+			break
+		}
+		if token.Kind != TokenAtom || !strings.HasPrefix(token.Text, ":") {
+			reader.errorf(macro.Start, "%s at %s features must be keywords", macro.Text, macro.Start)
+		}
+		if reader.Peek().Kind == TokenClose {
+			reader.errorf(macro.Start, "%s at %s requires an even number of forms", macro.Text, macro.Start)
+		}
+		if selected == nil && reader.hasFeature(token.Text) {
+			form := readForm(reader)
+			selected = &form
+		} else {
+			skipForm(reader)
+		}
+	}
+	if selected == nil {
+		return nil
+	}
+	if macro.Text == "#?" {
+		return []Value{*selected}
+	}
+	if !isSequential(*selected) {
+		reader.errorf(macro.Start, "%s at %s can only splice a list or vector", macro.Text, macro.Start)
+	}
+	return selected.Elems()
+}
+
+// skipForm reads past the next form without parsing it, like Clojure's *suppress-read*. Delimiters must match and
+// strings must be closed, but numbers, regexes and tagged literals are not checked and data readers are not called.
+func skipForm(reader *Reader) {
+	token := reader.Peek()
+	if token.Kind == TokenOpen || token.Kind == TokenMacro {
+		defer reader.nest(token)()
+	}
+	reader.Next()
+	switch token.Kind {
+	case TokenEOF:
+		reader.incompletef(token.Start, "unexpected end of input at %s", token.Start)
+	case TokenClose:
+		reader.errorf(token.Start, "unexpected %s at %s", token.Text, token.Start)
+	case TokenOpen:
+		stopToken := closeTokens[token.Text]
+		for {
+			next := reader.Peek()
+			if next.Kind == TokenEOF {
+				reader.incompletef(token.Start, "unmatched %s opened at %s", token.Text, token.Start)
+			}
+			if next.Kind == TokenClose {
+				reader.Next()
+				if next.Text != stopToken {
+					reader.errorf(token.Start, "unmatched %s opened at %s", token.Text, token.Start)
+				}
+				return
+			}
+			skipForm(reader)
+		}
+	case TokenMacro:
+		if token.Text == "^" {
+			skipForm(reader) // the metadata before the form
+		}
+		skipForm(reader)
+	}
+}
+
+// readPlainForm parses the next form from the reader, excluding forms that can read as other than one form.
+func readPlainForm(reader *Reader) Value {
 	peekToken := reader.Peek()
-	switch peekToken {
+	switch peekToken.Kind {
+	case TokenEOF:
+		reader.incompletef(peekToken.Start, "unexpected end of input at %s", peekToken.Start)
+	case TokenClose:
+		reader.Next()
+		reader.errorf(peekToken.Start, "unexpected %s at %s", peekToken.Text, peekToken.Start)
+	case TokenOpen:
+		if peekToken.Text == "#(" {
+			return readFnLiteral(reader)
+		}
+		return readCollection(reader, peekToken.Text)
+	}
+	switch peekToken.Text {
+	case "^":
+		reader.Next()
+		meta := readMeta(reader, readForm(reader))
+		form := readForm(reader)
+		if !canHaveMeta(form) {
+			reader.errorf(peekToken.Start, "metadata at %s can only be applied to collections and symbols", peekToken.Start)
+		}
+		return mergeMeta(form, meta)
 	case "@", "'", "`", "~", "~@": // reader macros
 		syms := map[string]string{"@": "deref", "'": "quote", "`": "quasiquote", "~": "unquote", "~@": "splice-unquote"}
 		reader.Next()
-		return Value{Type: "list", Val: []Value{{Type: "symbol", Val: syms[peekToken]}, readForm(reader)}}
-	case "(", "[", "{":
-		return readCollection(reader, peekToken)
+		form := readForm(reader)
+		return Value{Type: KindList, Val: []Value{
+			{Type: KindSymbol, Val: syms[peekToken.Text], Span: reader.span(peekToken, peekToken)},
+			form,
+		}, Span: &Span{File: reader.File, Start: peekToken.Start, End: form.Span.End}}
+	}
+	if peekToken.Kind == TokenMacro && strings.HasPrefix(peekToken.Text, "#") {
+		return readTagged(reader)
 	}
 	return readAtom(reader)
 }
+
+// readTagged reads a `#tag form` tagged literal and passes the form to the data reader of the tag. Literals with
+// unknown tags read as tagged-literal values.
+func readTagged(reader *Reader) Value {
+	tag := reader.Next()
+	form := readForm(reader)
+	span := &Span{File: reader.File, Start: tag.Start, End: form.Span.End}
+	name := tag.Text[1:]
+
+	fn, ok := lookupDataReader(name)
+	if reader.DataReader != nil {
+		if readerFn, readerOk := reader.DataReader(name); readerOk {
+			fn, ok = readerFn, true
+		}
+	}
+	if !ok {
+		return Value{Type: KindTaggedLiteral, Val: TaggedLiteral{Tag: name, Form: form}, Span: span}
+	}
+	v := callDataReader(reader, tag, fn, form)
+	if v.Span == nil {
+		v.Span = span
+	}
+	return v
+}
+
+// callDataReader calls a data reader, turning its panics into syntax errors at the tag.
+func callDataReader(reader *Reader, tag Token, fn func(Value) Value, form Value) (v Value) {
+	defer func() {
+		switch rec := recover().(type) {
+		case nil:
+		case *EvalError:
+			reader.errorf(tag.Start, "%s at %s: %v", tag.Text, tag.Start, rec.Err)
+		case Value:
+			// thrown mal values
+			reader.errorf(tag.Start, "%s at %s: %s", tag.Text, tag.Start, Print(rec, true))
+		default:
+			reader.errorf(tag.Start, "%s at %s: %v", tag.Text, tag.Start, rec)
+		}
+	}()
+	return fn(form)
+}
+
+// readMeta expands the shorthands of the `^` reader macro. `^:kw` is `^{:kw true}` and `^sym` or `^"str"` is
+// `^{:tag sym}`.
+func readMeta(reader *Reader, meta Value) Value {
+	switch meta.Type {
+	case KindHashMap:
+		return meta
+	case KindKeyword:
+		return keywordMap(map[string]Value{meta.Str(): {Type: KindBoolean, Val: true}})
+	case KindSymbol, KindString:
+		return keywordMap(map[string]Value{":tag": meta})
+	}
+	reader.errorf(meta.Span.Start, "metadata at %s must be a map, keyword, symbol or string", meta.Span.Start)
+	return Value{}
+}
+
+// readFnLiteral reads a `#(...)` anonymous function literal and expands it to a `fn*`. `%` or `%1`..`%n` are the
+// positional parameters and `%&` is the rest parameter, e.g. `#(+ % %2)` is `(fn* [%1 %2] (+ %1 %2))`.
+func readFnLiteral(reader *Reader) Value {
+	open := reader.Peek()
+	if reader.inFn {
+		reader.errorf(open.Start, "nested #() at %s is not allowed", open.Start)
+	}
+	reader.inFn = true
+	defer func() { reader.inFn = false }()
+	body := readCollection(reader, open.Text)
+
+	var arity int
+	var hasRest bool
+	body = replaceFnArgs(body, &arity, &hasRest)
+	var params []Value
+	for i := 1; i <= arity; i++ {
+		params = append(params, Value{Type: KindSymbol, Val: fmt.Sprintf("%%%d", i)})
+	}
+	if hasRest {
+		params = append(params, Value{Type: KindSymbol, Val: "&"}, Value{Type: KindSymbol, Val: "%&"})
+	}
+	return Value{Type: KindList, Val: []Value{
+		{Type: KindSymbol, Val: "fn*", Span: body.Span},
+		{Type: KindVector, Val: params, Span: body.Span},
+		body,
+	}, Span: body.Span}
+}
+
+// replaceFnArgs replaces `%` with `%1` in a #() body, recording the highest positional parameter and whether the rest
+// parameter is used.
+func replaceFnArgs(v Value, arity *int, hasRest *bool) Value {
+	switch v.Type {
+	case KindSymbol:
+		name := v.Str()
+		switch {
+		case name == "%":
+			v.Val = "%1"
+			name = "%1"
+		case name == "%&":
+			*hasRest = true
+			return v
+		case !strings.HasPrefix(name, "%"):
+			return v
+		}
+		if n, err := strconv.Atoi(name[1:]); err == nil && n > 0 && n > *arity {
+			*arity = n
+		}
+		return v
+	case KindList, KindVector:
+		var elems []Value
+		for _, elem := range v.Elems() {
+			elems = append(elems, replaceFnArgs(elem, arity, hasRest))
+		}
+		v.Val = elems
+		return v
+	case KindHashMap:
+		var kvs []Value
+		for _, entry := range mapEntries(v) {
+			kvs = append(kvs, replaceFnArgs(entry.Key, arity, hasRest), replaceFnArgs(entry.Val, arity, hasRest))
+		}
+		m := newHashMap(kvs)
+		m.Span, m.Meta = v.Span, v.Meta
+		return m
+	case KindSet:
+		var elems []Value
+		for _, elem := range v.Val.(map[string]Value) {
+			elems = append(elems, replaceFnArgs(elem, arity, hasRest))
+		}
+		set := newSet(elems)
+		set.Span, set.Meta = v.Span, v.Meta
+		return set
+	}
+	return v
+}
@@ -0,0 +1,36 @@
+package malarkey_test
+
+import (
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+func TestReadPartial(t *testing.T) {
+	tests := []struct {
+		src    string
+		forms  int
+		rest   string
+		status mal.ReadStatus
+	}{
+		{"", 0, "", mal.ReadComplete},
+		{"(a) [b] ; c", 2, "", mal.ReadComplete},
+		{"(a", 0, "(a", mal.ReadIncomplete},
+		{`x "abc`, 1, ` "abc`, mal.ReadIncomplete},
+		{"(a) )", 1, " )", mal.ReadInvalid},
+		{"#?(:x", 0, "#?(:x", mal.ReadIncomplete},
+		{"#?(:x 1.5M", 0, "#?(:x 1.5M", mal.ReadIncomplete},
+		{"(a] b", 0, "(a] b", mal.ReadInvalid},
+		{`"""raw`, 0, `"""raw`, mal.ReadIncomplete},
+	}
+	for _, test := range tests {
+		forms, rest, status, err := mal.ReadPartial(test.src)
+		if len(forms) != test.forms || rest != test.rest || status != test.status {
+			t.Errorf("ReadPartial(%q) = %d forms, rest %q, %s; want %d forms, rest %q, %s",
+				test.src, len(forms), rest, status, test.forms, test.rest, test.status)
+		}
+		if (err != nil) != (status == mal.ReadInvalid) {
+			t.Errorf("ReadPartial(%q) status %s with error %v", test.src, status, err)
+		}
+	}
+}
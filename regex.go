@@ -0,0 +1,82 @@
+package malarkey
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseRegex parses a regex literal token, `#"..."`. The pattern is taken as written, without string escapes.
+func parseRegex(token string) (Value, error) {
+	re, err := regexp.Compile(token[2 : len(token)-1])
+	if err != nil {
+		return Value{}, fmt.Errorf("invalid regex: %v", err)
+	}
+	return Value{Type: KindRegex, Val: re}, nil
+}
+
+// hasNamedGroups returns true if any capture group of the regex is named.
+func hasNamedGroups(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGroups returns the match and its groups as a vector. groups that did not participate in the match are nil.
+// loc is a result of FindStringSubmatchIndex.
+func matchGroups(s string, loc []int) Value {
+	var groups []Value
+	for i := 0; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			groups = append(groups, Value{Type: KindNil, Val: nil})
+		} else {
+			groups = append(groups, Value{Type: KindString, Val: s[loc[i]:loc[i+1]]})
+		}
+	}
+	return Value{Type: KindVector, Val: groups}
+}
+
+// matchValue returns a match the way re-find does. It is the matched string if the regex has no groups, a hash-map of
+// keywordized group names to matches if it has named groups, and otherwise a vector of the match and its groups.
+func matchValue(re *regexp.Regexp, s string, loc []int) Value {
+	if re.NumSubexp() == 0 {
+		return Value{Type: KindString, Val: s[loc[0]:loc[1]]}
+	}
+	groups := matchGroups(s, loc)
+	if !hasNamedGroups(re) {
+		return groups
+	}
+	kws := map[string]Value{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			kws[":"+name] = groups.Elems()[i]
+		}
+	}
+	return keywordMap(kws)
+}
+
+// anchored returns a regex that only matches the whole input.
+func anchored(re *regexp.Regexp) *regexp.Regexp {
+	return regexp.MustCompile(`^(?:` + re.String() + `)$`)
+}
+
+// replaceRegex replaces every match of re in s. replacement is a string, which may refer to groups as $1 or ${name},
+// or a function called with the match as re-find returns it.
+func replaceRegex(s string, re *regexp.Regexp, replacement Value) string {
+	if replacement.Type == KindString {
+		return re.ReplaceAllString(s, replacement.Str())
+	}
+	fn := getFn(replacement)
+	var b strings.Builder
+	var last int
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(Print(fn(matchValue(re, s, loc)), false))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package malarkey
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits bounds the resources used to read untrusted source text. Zero fields are unlimited.
+type Limits struct {
+	MaxDepth  int // nesting depth of collections and reader macros, e.g. `[[1]]` and `'[1]` have a depth of 2
+	MaxBytes  int // size of the source text in bytes
+	MaxTokens int // number of tokens, excluding whitespace and comments
+}
+
+// DefaultLimits are the limits of `(read-string s {:safe true})`. They are generous for data while keeping deeply
+// nested or very large input from exhausting the stack or memory.
+var DefaultLimits = Limits{MaxDepth: 1000, MaxBytes: 1 << 20, MaxTokens: 100000}
+
+// LimitError is an error for source text that exceeds one of its read Limits.
+type LimitError struct {
+	File  string
+	Pos   Pos
+	Limit string // "depth", "bytes" or "tokens"
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	msg := fmt.Sprintf("input exceeds the max %s of %d at %s", e.Limit, e.Max, e.Pos)
+	if e.File == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", e.File, msg)
+}
+
+// ReadSafe parses source text of a single form within limits. Unlike Read, malformed input and input that exceeds a
+// limit are returned as a *SyntaxError or *LimitError instead of panicking.
+func ReadSafe(src string, limits Limits) (form Value, err error) {
+	defer recoverErr(&err)
+	reader := NewReader(strings.NewReader(src), "")
+	reader.Limits = limits
+	return readOnly(reader), nil
+}
+
+// limitsOption returns the limits of a read-string options hash-map. `:safe true` starts from DefaultLimits and
+// :max-depth, :max-bytes and :max-tokens set individual limits.
+func limitsOption(opts Value) Limits {
+	var limits Limits
+	if safe, ok := mapGet(opts, Value{Type: KindKeyword, Val: ":safe"}); ok && safe.Type == KindBoolean && safe.Bool() {
+		limits = DefaultLimits
+	}
+	for key, limit := range map[string]*int{":max-depth": &limits.MaxDepth, ":max-bytes": &limits.MaxBytes, ":max-tokens": &limits.MaxTokens} {
+		if v, ok := mapGet(opts, Value{Type: KindKeyword, Val: key}); ok {
+			if v.Type != KindInteger || v.Int() < 0 {
+				panic(fmt.Sprintf("option %s must be a non-negative integer", key))
+			}
+			*limit = int(v.Int())
+		}
+	}
+	return limits
+}
@@ -0,0 +1,43 @@
+package malarkey_test
+
+import (
+	"errors"
+	"testing"
+
+	mal "github.com/elh/mal-arkey"
+)
+
+func TestReadSafe(t *testing.T) {
+	tests := []struct {
+		src    string
+		limits mal.Limits
+		limit  string // the exceeded limit, if any
+		syntax bool   // a syntax error
+	}{
+		{"[[1]]", mal.Limits{MaxDepth: 2}, "", false},
+		{"[[[1]]]", mal.Limits{MaxDepth: 2}, "depth", false},
+		{"'[1]", mal.Limits{MaxDepth: 1}, "depth", false},
+		{"(1 2 3)", mal.Limits{MaxTokens: 5}, "", false},
+		{"(1 2 3 4)", mal.Limits{MaxTokens: 5}, "tokens", false},
+		{`"abcdef"`, mal.Limits{MaxBytes: 4}, "bytes", false},
+		{"(a", mal.DefaultLimits, "", true},
+		{"1 2", mal.DefaultLimits, "", true},
+	}
+	for _, test := range tests {
+		_, err := mal.ReadSafe(test.src, test.limits)
+		var limitErr *mal.LimitError
+		var syntaxErr *mal.SyntaxError
+		switch {
+		case test.limit != "":
+			if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+				t.Errorf("ReadSafe(%q) error %v, want the %s limit", test.src, err, test.limit)
+			}
+		case test.syntax:
+			if !errors.As(err, &syntaxErr) {
+				t.Errorf("ReadSafe(%q) error %v, want a syntax error", test.src, err)
+			}
+		case err != nil:
+			t.Errorf("ReadSafe(%q) error %v", test.src, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package malarkey
+
+import "fmt"
+
+// newSet creates a set of the given elements. Duplicates are dropped.
+func newSet(elems []Value) Value {
+	set := make(map[string]Value, len(elems))
+	for _, elem := range elems {
+		set[hashKey(elem)] = elem
+	}
+	return Value{Type: KindSet, Val: set}
+}
+
+// setElems returns the elements of a set.
+func setElems(s Value) []Value {
+	var elems []Value
+	for _, elem := range s.Val.(map[string]Value) {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+// sortedSetElems returns the elements of a set ordered by their hashKey, like sortedMapEntries.
+func sortedSetElems(s Value, n int) []Value {
+	set := s.Val.(map[string]Value)
+	keys := firstKeys(set, n)
+	elems := make([]Value, len(keys))
+	for i, k := range keys {
+		elems[i] = set[k]
+	}
+	return elems
+}
+
+// copySet returns a shallow copy of a set's entries so that it can be modified without mutating the original.
+func copySet(s Value) map[string]Value {
+	set := make(map[string]Value, len(s.Val.(map[string]Value)))
+	for k, elem := range s.Val.(map[string]Value) {
+		set[k] = elem
+	}
+	return set
+}
+
+// setUnion returns a set of the elements in any of the sets.
+func setUnion(sets ...Value) Value {
+	out := map[string]Value{}
+	for _, s := range sets {
+		for k, elem := range s.Val.(map[string]Value) {
+			out[k] = elem
+		}
+	}
+	return Value{Type: KindSet, Val: out}
+}
+
+// setIntersection returns a set of the elements in all of the sets.
+func setIntersection(first Value, rest ...Value) Value {
+	out := copySet(first)
+	for _, s := range rest {
+		other := s.Val.(map[string]Value)
+		for k := range out {
+			if _, ok := other[k]; !ok {
+				delete(out, k)
+			}
+		}
+	}
+	return Value{Type: KindSet, Val: out}
+}
+
+// setDifference returns a set of the elements of the first set that are in none of the others.
+func setDifference(first Value, rest ...Value) Value {
+	out := copySet(first)
+	for _, s := range rest {
+		for k := range s.Val.(map[string]Value) {
+			delete(out, k)
+		}
+	}
+	return Value{Type: KindSet, Val: out}
+}
+
+// isSubset returns true if every element of a is in b.
+func isSubset(a, b Value) bool {
+	bset := b.Val.(map[string]Value)
+	for k := range a.Val.(map[string]Value) {
+		if _, ok := bset[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// panic if there are fewer than min args or any arg is not a set. like validateArgs for variadic set functions.
+func validateSets(fn string, args []Value, min int) {
+	if len(args) < min {
+		panic(fmt.Sprintf("%s requires at least %d argument(s)", fn, min))
+	}
+	for i, arg := range args {
+		if arg.Type != KindSet {
+			panic(fmt.Sprintf("%s %d-idx argument must be of set type", fn, i))
+		}
+	}
+}
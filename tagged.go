@@ -0,0 +1,73 @@
+package malarkey
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaggedLiteral is a `#tag form` literal whose tag has no data reader.
+type TaggedLiteral struct {
+	Tag  string // tag symbol without the `#`
+	Form Value
+}
+
+var (
+	dataReadersMu sync.RWMutex
+	dataReaders   = map[string]func(Value) Value{
+		"inst": readInst,
+		"uuid": readUUID,
+	}
+)
+
+// RegisterDataReader registers the data reader of the `#tag form` literal. The reader is called with the form
+// following the tag and returns the value read in its place. Readers may panic to reject the form. Registering a tag
+// again replaces its reader, including the built-in `inst` and `uuid` readers.
+func RegisterDataReader(tag string, fn func(Value) Value) {
+	dataReadersMu.Lock()
+	defer dataReadersMu.Unlock()
+	dataReaders[tag] = fn
+}
+
+func lookupDataReader(tag string) (func(Value) Value, bool) {
+	dataReadersMu.RLock()
+	defer dataReadersMu.RUnlock()
+	fn, ok := dataReaders[tag]
+	return fn, ok
+}
+
+// layouts accepted by #inst, from the most to the least precise. times without an offset are UTC.
+var instLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", "2006-01", "2006"}
+
+// readInst reads `#inst "2026-10-16T00:00:00Z"` as an inst.
+func readInst(form Value) Value {
+	if form.Type != KindString {
+		panic("#inst requires a string")
+	}
+	for _, layout := range instLayouts {
+		if t, err := time.Parse(layout, form.Str()); err == nil {
+			return Value{Type: KindInst, Val: t.UTC()}
+		}
+	}
+	panic(fmt.Sprintf("invalid #inst %s: expected an RFC 3339 timestamp", escapeString(form.Str())))
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// readUUID reads `#uuid "..."` as a uuid. uuids are kept in lowercase.
+func readUUID(form Value) Value {
+	if form.Type != KindString {
+		panic("#uuid requires a string")
+	}
+	if !uuidRegex.MatchString(form.Str()) {
+		panic(fmt.Sprintf("invalid #uuid %s", escapeString(form.Str())))
+	}
+	return Value{Type: KindUUID, Val: strings.ToLower(form.Str())}
+}
+
+// printInst returns the RFC 3339 timestamp of an inst.
+func printInst(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}